@@ -67,10 +67,10 @@ func dataSourceDatabaseDatabaseRead(d *schema.ResourceData, meta interface{}) er
 		return fmt.Errorf("error while getting resource: %s", err)
 	}
 	var dbmsType string
-	if _, ok := dbmsResp.(instanceResp); ok {
+	if _, ok := dbmsResp.(*instanceResp); ok {
 		dbmsType = dbmsTypeInstance
 	}
-	if _, ok := dbmsResp.(dbClusterResp); ok {
+	if _, ok := dbmsResp.(*dbClusterResp); ok {
 		dbmsType = dbmsTypeCluster
 	}
 	exists, err := databaseDatabaseExists(DatabaseV1Client, dbmsID, databaseName, dbmsType)
@@ -73,10 +73,10 @@ func dataSourceDatabaseUserRead(d *schema.ResourceData, meta interface{}) error
 		return fmt.Errorf("error while getting resource: %s", err)
 	}
 	var dbmsType string
-	if _, ok := dbmsResp.(instanceResp); ok {
+	if _, ok := dbmsResp.(*instanceResp); ok {
 		dbmsType = dbmsTypeInstance
 	}
-	if _, ok := dbmsResp.(dbClusterResp); ok {
+	if _, ok := dbmsResp.(*dbClusterResp); ok {
 		dbmsType = dbmsTypeCluster
 	}
 	exists, userObj, err := databaseUserExists(DatabaseV1Client, dbmsID, userName, dbmsType)
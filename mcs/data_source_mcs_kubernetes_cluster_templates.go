@@ -2,16 +2,49 @@ package mcs
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"time"
 
+	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 )
 
 func dataSourceKubernetesClusterTemplates() *schema.Resource {
 	return &schema.Resource{
 		Read: dataSourceMcsClusterTemplatesRead,
 		Schema: map[string]*schema.Schema{
+			"name_regex": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringIsValidRegExp,
+			},
+			"version_regex": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringIsValidRegExp,
+			},
+			"min_version": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+					if _, err := version.NewVersion(val.(string)); err != nil {
+						errs = append(errs, fmt.Errorf("%q must be a semantic version: %s", key, err))
+					}
+					return
+				},
+			},
+			"exclude_deprecated": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"most_recent": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
 			"cluster_templates": {
 				Type:     schema.TypeList,
 				Computed: true,
@@ -29,6 +62,19 @@ func dataSourceKubernetesClusterTemplates() *schema.Resource {
 							Type:     schema.TypeString,
 							Required: true,
 						},
+						"deprecated_at": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"labels": {
+							Type:     schema.TypeMap,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"image": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
 					},
 				},
 			},
@@ -37,9 +83,12 @@ func dataSourceKubernetesClusterTemplates() *schema.Resource {
 }
 
 type clusterTemplateResponse struct {
-	Version string
-	UUID    string
-	Name    string
+	Version      string
+	UUID         string
+	Name         string
+	DeprecatedAt string
+	Labels       map[string]string
+	Image        string
 }
 
 type clusterTemplateFlatSchema []map[string]interface{}
@@ -51,11 +100,71 @@ func flattenClusterTemplates(templates []clusterTemplateResponse) clusterTemplat
 			"name":                  template.Name,
 			"cluster_template_uuid": template.UUID,
 			"version":               template.Version,
+			"deprecated_at":         template.DeprecatedAt,
+			"labels":                template.Labels,
+			"image":                 template.Image,
 		})
 	}
 	return flatSchema
 }
 
+// clusterTemplatesFilter selects cluster templates by the data source arguments.
+type clusterTemplatesFilter struct {
+	nameRegex         *regexp.Regexp
+	versionRegex      *regexp.Regexp
+	minVersion        *version.Version
+	excludeDeprecated bool
+	mostRecent        bool
+}
+
+func (f clusterTemplatesFilter) match(t clusterTemplate, now time.Time) bool {
+	if f.nameRegex != nil && !f.nameRegex.MatchString(t.Name) {
+		return false
+	}
+	if f.versionRegex != nil && !f.versionRegex.MatchString(t.Version) {
+		return false
+	}
+	if f.minVersion != nil {
+		v, err := version.NewVersion(t.Version)
+		if err != nil || v.LessThan(f.minVersion) {
+			return false
+		}
+	}
+	if f.excludeDeprecated && isClusterTemplateDeprecated(&t, now) {
+		return false
+	}
+	return true
+}
+
+// apply returns the matching templates, or only the one with the newest
+// kubernetes version if mostRecent is set. Templates with versions which are
+// not semantic versions are considered older than any other ones.
+func (f clusterTemplatesFilter) apply(templates []clusterTemplate, now time.Time) []clusterTemplate {
+	filtered := make([]clusterTemplate, 0, len(templates))
+	for _, t := range templates {
+		if f.match(t, now) {
+			filtered = append(filtered, t)
+		}
+	}
+	if !f.mostRecent || len(filtered) == 0 {
+		return filtered
+	}
+
+	recent := filtered[0]
+	recentVersion, _ := version.NewVersion(recent.Version)
+	for _, t := range filtered[1:] {
+		v, err := version.NewVersion(t.Version)
+		if err != nil {
+			continue
+		}
+		if recentVersion == nil || v.GreaterThan(recentVersion) ||
+			v.Equal(recentVersion) && t.CreatedAt.After(recent.CreatedAt) {
+			recent, recentVersion = t, v
+		}
+	}
+	return []clusterTemplate{recent}
+}
+
 func dataSourceMcsClusterTemplatesRead(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(configer)
 	client, err := config.ContainerInfraV1Client(config.GetRegion())
@@ -63,17 +172,39 @@ func dataSourceMcsClusterTemplatesRead(d *schema.ResourceData, meta interface{})
 		return fmt.Errorf("failed to init identity v3 client: %s", err)
 	}
 
+	filter := clusterTemplatesFilter{
+		excludeDeprecated: d.Get("exclude_deprecated").(bool),
+		mostRecent:        d.Get("most_recent").(bool),
+	}
+	if v, ok := d.GetOk("name_regex"); ok {
+		filter.nameRegex = regexp.MustCompile(v.(string))
+	}
+	if v, ok := d.GetOk("version_regex"); ok {
+		filter.versionRegex = regexp.MustCompile(v.(string))
+	}
+	if v, ok := d.GetOk("min_version"); ok {
+		filter.minVersion = version.Must(version.NewVersion(v.(string)))
+	}
+
 	templates, err := clusterTemplateList(client).Extract()
 	if err != nil {
 		return fmt.Errorf("failed to list cluster templates: %s", err)
 	}
 
-	clusterTemplates := make([]clusterTemplateResponse, 0, len(templates))
-	for _, t := range templates {
+	filtered := filter.apply(templates, time.Now())
+	clusterTemplates := make([]clusterTemplateResponse, 0, len(filtered))
+	for _, t := range filtered {
+		var deprecatedAt string
+		if !t.DeprecatedAt.IsZero() {
+			deprecatedAt = t.DeprecatedAt.Format(time.RFC3339)
+		}
 		clusterTemplates = append(clusterTemplates, clusterTemplateResponse{
-			UUID:    t.UUID,
-			Name:    t.Name,
-			Version: t.Version,
+			UUID:         t.UUID,
+			Name:         t.Name,
+			Version:      t.Version,
+			DeprecatedAt: deprecatedAt,
+			Labels:       t.Labels,
+			Image:        t.ImageID,
 		})
 	}
 
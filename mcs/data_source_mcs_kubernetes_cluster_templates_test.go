@@ -2,11 +2,15 @@ package mcs
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestAccKubernetesDataSourceClusterTemplates(t *testing.T) {
@@ -28,6 +32,22 @@ func TestAccKubernetesDataSourceClusterTemplates(t *testing.T) {
 				},
 			},
 		},
+		"most recent": {
+			name: "data.mcs_kubernetes_clustertemplates.recent",
+			testCase: resource.TestCase{
+				Providers: testAccProviders,
+				Steps: []resource.TestStep{
+					{
+						Config: testAccDataSourceMCSKubernetesClusterTemplatesConfigMostRecent(),
+						Check: resource.ComposeTestCheckFunc(
+							resource.TestCheckResourceAttr("data.mcs_kubernetes_clustertemplates.recent", "cluster_templates.#", "1"),
+							resource.TestCheckResourceAttr("data.mcs_kubernetes_clustertemplates.recent", "cluster_templates.0.deprecated_at", ""),
+							resource.TestCheckResourceAttrSet("data.mcs_kubernetes_clustertemplates.recent", "cluster_templates.0.image"),
+						),
+					},
+				},
+			},
+		},
 	}
 
 	for name := range tests {
@@ -44,6 +64,15 @@ data "mcs_kubernetes_clustertemplates" "empty" {}
 `
 }
 
+func testAccDataSourceMCSKubernetesClusterTemplatesConfigMostRecent() string {
+	return `
+data "mcs_kubernetes_clustertemplates" "recent" {
+  exclude_deprecated = true
+  most_recent        = true
+}
+`
+}
+
 func testAccDataSourceMCSKubernetesClusterTemplatesCheck(resourceName string) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[resourceName]
@@ -69,3 +98,66 @@ func testAccDataSourceMCSKubernetesClusterTemplatesCheck(resourceName string) re
 		return nil
 	}
 }
+
+func TestClusterTemplatesFilter(t *testing.T) {
+	now := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	template := func(name, version string, createdAt, deprecatedAt time.Time) clusterTemplate {
+		var t clusterTemplate
+		t.Name, t.UUID, t.CreatedAt = name, name, createdAt
+		t.Version, t.DeprecatedAt = version, deprecatedAt
+		return t
+	}
+	templates := []clusterTemplate{
+		template("k8s-1.19.9", "1.19.9", now.AddDate(-1, 0, 0), now.AddDate(0, -1, 0)),
+		template("k8s-1.21.4", "1.21.4", now.AddDate(0, -2, 0), now.AddDate(0, 1, 0)),
+		template("k8s-1.21.4-new", "1.21.4", now.AddDate(0, -1, 0), time.Time{}),
+		template("k8s-1.20.4", "1.20.4", now.AddDate(0, -3, 0), time.Time{}),
+		template("custom", "custom", now, time.Time{}),
+	}
+	names := func(templates []clusterTemplate) []string {
+		var names []string
+		for _, t := range templates {
+			names = append(names, t.Name)
+		}
+		return names
+	}
+
+	tests := map[string]struct {
+		filter   clusterTemplatesFilter
+		expected []string
+	}{
+		"no filter": {
+			filter:   clusterTemplatesFilter{},
+			expected: []string{"k8s-1.19.9", "k8s-1.21.4", "k8s-1.21.4-new", "k8s-1.20.4", "custom"},
+		},
+		"name regex": {
+			filter:   clusterTemplatesFilter{nameRegex: regexp.MustCompile(`new$`)},
+			expected: []string{"k8s-1.21.4-new"},
+		},
+		"version regex": {
+			filter:   clusterTemplatesFilter{versionRegex: regexp.MustCompile(`^1\.2\d\.`)},
+			expected: []string{"k8s-1.21.4", "k8s-1.21.4-new", "k8s-1.20.4"},
+		},
+		"min version": {
+			filter:   clusterTemplatesFilter{minVersion: version.Must(version.NewVersion("1.20"))},
+			expected: []string{"k8s-1.21.4", "k8s-1.21.4-new", "k8s-1.20.4"},
+		},
+		"exclude deprecated": {
+			filter:   clusterTemplatesFilter{excludeDeprecated: true},
+			expected: []string{"k8s-1.21.4", "k8s-1.21.4-new", "k8s-1.20.4", "custom"},
+		},
+		"most recent": {
+			filter:   clusterTemplatesFilter{mostRecent: true},
+			expected: []string{"k8s-1.21.4-new"},
+		},
+		"most recent without matches": {
+			filter: clusterTemplatesFilter{nameRegex: regexp.MustCompile(`^none$`), mostRecent: true},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, names(tt.filter.apply(templates, now)))
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package mcs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+func dataSourceKubernetesClusterUpgradePath() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceKubernetesClusterUpgradePathRead,
+		Schema: map[string]*schema.Schema{
+			"region": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"cluster_id": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"target_version": {
+				Type:     schema.TypeString,
+				Required: true,
+				ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+					if _, err := version.NewVersion(val.(string)); err != nil {
+						errs = append(errs, fmt.Errorf("%q must be a semantic version: %s", key, err))
+					}
+					return
+				},
+			},
+			"current_version": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"cluster_template_ids": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"versions": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func dataSourceKubernetesClusterUpgradePathRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(configer)
+	containerInfraClient, err := config.ContainerInfraV1Client(getRegion(d, config))
+	if err != nil {
+		return fmt.Errorf("error creating container infra client: %s", err)
+	}
+
+	clusterID := d.Get("cluster_id").(string)
+	c, err := clusterGet(containerInfraClient, clusterID).Extract()
+	if err != nil {
+		return fmt.Errorf("error getting mcs_kubernetes_cluster %s: %s", clusterID, err)
+	}
+
+	templates, err := clusterTemplateList(containerInfraClient).Extract()
+	if err != nil {
+		return fmt.Errorf("error listing cluster templates: %s", err)
+	}
+
+	var current *clusterTemplate
+	for i := range templates {
+		if templates[i].UUID == c.ClusterTemplateID {
+			current = &templates[i]
+		}
+	}
+	if current == nil {
+		return fmt.Errorf("cluster template %s of mcs_kubernetes_cluster %s is not found", c.ClusterTemplateID, clusterID)
+	}
+
+	path, err := clusterUpgradePath(templates, current, d.Get("target_version").(string), time.Now())
+	if err != nil {
+		return fmt.Errorf("error computing upgrade path of mcs_kubernetes_cluster %s: %s", clusterID, err)
+	}
+
+	templateIDs := make([]string, len(path))
+	versions := make([]string, len(path))
+	for i, t := range path {
+		templateIDs[i] = t.UUID
+		versions[i] = t.Version
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", c.UUID, d.Get("target_version").(string)))
+	d.Set("current_version", current.Version)
+	d.Set("cluster_template_ids", templateIDs)
+	d.Set("versions", versions)
+	d.Set("region", getRegion(d, config))
+
+	return nil
+}
+
+// clusterUpgradePath returns templates to upgrade the cluster through from the
+// current template to the target version, one minor version at a time.
+// The newest template of every minor version is chosen and deprecated ones
+// are skipped. The target version matches templates by the given segments,
+// e.g. 1.21 matches any 1.21.x. The path is empty if the current version
+// matches the target one.
+func clusterUpgradePath(templates []clusterTemplate, current *clusterTemplate, target string, now time.Time) ([]clusterTemplate, error) {
+	currentVersion, err := version.NewVersion(current.Version)
+	if err != nil {
+		return nil, fmt.Errorf("current kubernetes version %q is not a semantic version", current.Version)
+	}
+	targetVersion, err := version.NewVersion(target)
+	if err != nil {
+		return nil, fmt.Errorf("target kubernetes version %q is not a semantic version", target)
+	}
+
+	segments := len(strings.Split(target, "."))
+	if segments > 3 {
+		segments = 3
+	}
+	targetSegments := targetVersion.Segments()
+	matchesTarget := func(v *version.Version) bool {
+		s := v.Segments()
+		for i := 0; i < segments; i++ {
+			if s[i] != targetSegments[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	currentSegments := currentVersion.Segments()
+	switch {
+	case matchesTarget(currentVersion):
+		return []clusterTemplate{}, nil
+	case targetVersion.LessThan(currentVersion):
+		return nil, fmt.Errorf("kubernetes version can't be downgraded from %s to %s", current.Version, target)
+	case targetSegments[0] != currentSegments[0]:
+		return nil, fmt.Errorf("kubernetes major version can't be upgraded from %s to %s", current.Version, target)
+	}
+
+	var path []clusterTemplate
+	for minor := currentSegments[1]; minor <= targetSegments[1]; minor++ {
+		last := minor == targetSegments[1]
+		if minor == currentSegments[1] && !last {
+			continue
+		}
+
+		var step *clusterTemplate
+		var stepVersion *version.Version
+		for i := range templates {
+			t := &templates[i]
+			v, err := version.NewVersion(t.Version)
+			if err != nil || isClusterTemplateDeprecated(t, now) {
+				continue
+			}
+			s := v.Segments()
+			if s[0] != currentSegments[0] || s[1] != minor || !v.GreaterThan(currentVersion) || last && !matchesTarget(v) {
+				continue
+			}
+			if step == nil || v.GreaterThan(stepVersion) || v.Equal(stepVersion) && t.CreatedAt.After(step.CreatedAt) {
+				step, stepVersion = t, v
+			}
+		}
+		if step == nil {
+			missing := fmt.Sprintf("%d.%d", currentSegments[0], minor)
+			if last {
+				missing = target
+			}
+			return nil, fmt.Errorf("no cluster template of kubernetes version %s is available", missing)
+		}
+		path = append(path, *step)
+	}
+	return path, nil
+}
@@ -0,0 +1,154 @@
+package mcs
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccKubernetesClusterUpgradePathDataSource_basic(t *testing.T) {
+	if nextClusterTemplateID == "" {
+		t.Skip("NEXT_CLUSTER_TEMPLATE_ID is not set, skipping cluster upgrade path test.")
+	}
+
+	clusterName := "testcluster" + acctest.RandStringFromCharSet(8, acctest.CharSetAlphaNum)
+	datasourceName := "data.mcs_kubernetes_cluster_upgrade_path." + clusterName
+
+	createClusterFixture := clusterFixture(clusterName, clusterTemplateID, osFlavorID,
+		osKeypairName, osNetworkID, osSubnetworkID, "MS1", 1)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                  func() { testAccPreCheckKubernetes(t) },
+		Providers:                 testAccProviders,
+		CheckDestroy:              testAccCheckKubernetesClusterDestroy,
+		PreventPostDestroyRefresh: true,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccKubernetesClusterUpgradePathDataSourceBasic(
+					testAccKubernetesClusterBasic(createClusterFixture), clusterName,
+				),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(datasourceName, "cluster_template_ids.#", "1"),
+					resource.TestCheckResourceAttr(datasourceName, "cluster_template_ids.0", nextClusterTemplateID),
+					resource.TestCheckResourceAttrPair(
+						datasourceName, "versions.0",
+						"data.mcs_kubernetes_clustertemplate.next", "version",
+					),
+				),
+			},
+		},
+	})
+}
+
+func testAccKubernetesClusterUpgradePathDataSourceBasic(clusterResource, clusterName string) string {
+	return fmt.Sprintf(`
+%s
+
+data "mcs_kubernetes_clustertemplate" "next" {
+  cluster_template_uuid = "%s"
+}
+
+data "mcs_kubernetes_cluster_upgrade_path" "%s" {
+  cluster_id     = mcs_kubernetes_cluster.%[3]s.id
+  target_version = data.mcs_kubernetes_clustertemplate.next.version
+}
+`, clusterResource, nextClusterTemplateID, clusterName)
+}
+
+func TestClusterUpgradePath(t *testing.T) {
+	now := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	template := func(id, version string, createdAt, deprecatedAt time.Time) clusterTemplate {
+		var t clusterTemplate
+		t.UUID, t.Version, t.CreatedAt, t.DeprecatedAt = id, version, createdAt, deprecatedAt
+		return t
+	}
+	templates := []clusterTemplate{
+		template("1.19.9", "1.19.9", now.AddDate(-1, 0, 0), time.Time{}),
+		template("1.20.4", "1.20.4", now.AddDate(0, -6, 0), time.Time{}),
+		template("1.20.7", "1.20.7", now.AddDate(0, -5, 0), time.Time{}),
+		template("1.20.9", "1.20.9", now.AddDate(0, -4, 0), now.AddDate(0, -1, 0)),
+		template("1.21.4", "1.21.4", now.AddDate(0, -3, 0), time.Time{}),
+		template("1.21.4-rebuild", "1.21.4", now.AddDate(0, -2, 0), time.Time{}),
+		template("1.22.2", "1.22.2", now.AddDate(0, -2, 0), time.Time{}),
+		template("1.22.3", "1.22.3", now.AddDate(0, -1, 0), time.Time{}),
+		template("1.24.1", "1.24.1", now, time.Time{}),
+		template("custom", "custom", now, time.Time{}),
+	}
+	ids := func(templates []clusterTemplate) []string {
+		ids := []string{}
+		for _, t := range templates {
+			ids = append(ids, t.UUID)
+		}
+		return ids
+	}
+
+	tests := map[string]struct {
+		current  string
+		target   string
+		expected []string
+		err      string
+	}{
+		"several minor versions": {
+			current:  "1.19.9",
+			target:   "1.22",
+			expected: []string{"1.20.7", "1.21.4-rebuild", "1.22.3"},
+		},
+		"exact target version": {
+			current:  "1.20.4",
+			target:   "1.22.2",
+			expected: []string{"1.21.4-rebuild", "1.22.2"},
+		},
+		"patch version": {
+			current:  "1.20.4",
+			target:   "1.20.7",
+			expected: []string{"1.20.7"},
+		},
+		"current version": {
+			current:  "1.21.4",
+			target:   "1.21",
+			expected: []string{},
+		},
+		"deprecated target": {
+			current: "1.20.4",
+			target:  "1.20.9",
+			err:     "no cluster template of kubernetes version 1.20.9 is available",
+		},
+		"missing minor version": {
+			current: "1.22.3",
+			target:  "1.24",
+			err:     "no cluster template of kubernetes version 1.23 is available",
+		},
+		"downgrade": {
+			current: "1.21.4",
+			target:  "1.20",
+			err:     "kubernetes version can't be downgraded from 1.21.4 to 1.20",
+		},
+		"major version": {
+			current: "1.21.4",
+			target:  "2.0",
+			err:     "kubernetes major version can't be upgraded from 1.21.4 to 2.0",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var current *clusterTemplate
+			for i := range templates {
+				if templates[i].UUID == tt.current {
+					current = &templates[i]
+				}
+			}
+			path, err := clusterUpgradePath(templates, current, tt.target, now)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, ids(path))
+		})
+	}
+}
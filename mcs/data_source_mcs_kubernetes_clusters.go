@@ -0,0 +1,207 @@
+package mcs
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+func dataSourceKubernetesClusters() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceKubernetesClustersRead,
+		Schema: map[string]*schema.Schema{
+			"region": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"name_regex": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringIsValidRegExp,
+			},
+			"status": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"cluster_template_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"labels": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"ids": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"clusters": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"cluster_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"status": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"cluster_template_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"master_count": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"api_address": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"labels": {
+							Type:     schema.TypeMap,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"created_at": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// clustersFilter selects clusters by the data source arguments. The labels
+// are not returned in the list of clusters, so they are matched against the
+// cluster details.
+type clustersFilter struct {
+	nameRegex         *regexp.Regexp
+	status            string
+	clusterTemplateID string
+	labels            map[string]string
+}
+
+func (f clustersFilter) match(c cluster) bool {
+	if f.nameRegex != nil && !f.nameRegex.MatchString(c.Name) {
+		return false
+	}
+	if f.status != "" && string(c.NewStatus) != f.status {
+		return false
+	}
+	if f.clusterTemplateID != "" && c.ClusterTemplateID != f.clusterTemplateID {
+		return false
+	}
+	return true
+}
+
+func (f clustersFilter) matchLabels(c cluster) bool {
+	for k, v := range f.labels {
+		if value, ok := c.Labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// apply returns the details of the matching clusters from the list.
+func (f clustersFilter) apply(clusters []cluster, get func(id string) (*cluster, error)) ([]cluster, error) {
+	filtered := make([]cluster, 0, len(clusters))
+	for _, c := range clusters {
+		if !f.match(c) {
+			continue
+		}
+		found, err := get(c.UUID)
+		if err != nil {
+			if _, ok := err.(gophercloud.ErrDefault404); ok {
+				log.Printf("[DEBUG] Cluster %s is deleted after listing", c.UUID)
+				continue
+			}
+			return nil, fmt.Errorf("error retrieving mcs_kubernetes_cluster %s: %s", c.UUID, err)
+		}
+		if f.matchLabels(*found) {
+			filtered = append(filtered, *found)
+		}
+	}
+	return filtered, nil
+}
+
+func flattenClusters(clusters []cluster) []map[string]interface{} {
+	flatSchema := make([]map[string]interface{}, 0, len(clusters))
+	for _, c := range clusters {
+		flatSchema = append(flatSchema, map[string]interface{}{
+			"cluster_id":          c.UUID,
+			"name":                c.Name,
+			"status":              string(c.NewStatus),
+			"cluster_template_id": c.ClusterTemplateID,
+			"master_count":        c.MasterCount,
+			"api_address":         c.APIAddress,
+			"labels":              c.Labels,
+			"created_at":          c.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return flatSchema
+}
+
+func dataSourceKubernetesClustersRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(configer)
+	containerInfraClient, err := config.ContainerInfraV1Client(getRegion(d, config))
+	if err != nil {
+		return fmt.Errorf("error creating container infra client: %s", err)
+	}
+
+	labels, err := extractKubernetesLabelsMap(d.Get("labels").(map[string]interface{}))
+	if err != nil {
+		return err
+	}
+	filter := clustersFilter{
+		status:            d.Get("status").(string),
+		clusterTemplateID: d.Get("cluster_template_id").(string),
+		labels:            labels,
+	}
+	if v, ok := d.GetOk("name_regex"); ok {
+		filter.nameRegex = regexp.MustCompile(v.(string))
+	}
+
+	clusters, err := clusterList(containerInfraClient)
+	if err != nil {
+		return fmt.Errorf("error listing mcs_kubernetes_cluster: %s", err)
+	}
+
+	filtered, err := filter.apply(clusters, func(id string) (*cluster, error) {
+		return clusterGet(containerInfraClient, id).Extract()
+	})
+	if err != nil {
+		return err
+	}
+	ids := make([]string, len(filtered))
+	for i, c := range filtered {
+		ids[i] = c.UUID
+	}
+
+	d.SetId(strconv.FormatInt(time.Now().Unix(), 10))
+	d.Set("ids", ids)
+	if err := d.Set("clusters", flattenClusters(filtered)); err != nil {
+		return fmt.Errorf("error setting clusters: %s", err)
+	}
+	d.Set("region", getRegion(d, config))
+
+	return nil
+}
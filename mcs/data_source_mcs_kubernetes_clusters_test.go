@@ -0,0 +1,156 @@
+package mcs
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccKubernetesClustersDataSource_basic(t *testing.T) {
+	clusterName := "testcluster" + acctest.RandStringFromCharSet(8, acctest.CharSetAlphaNum)
+	datasourceName := "data.mcs_kubernetes_clusters." + clusterName
+
+	createClusterFixture := clusterFixture(clusterName, clusterTemplateID, osFlavorID,
+		osKeypairName, osNetworkID, osSubnetworkID, "MS1", 1)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                  func() { testAccPreCheckKubernetes(t) },
+		Providers:                 testAccProviders,
+		CheckDestroy:              testAccCheckKubernetesClusterDestroy,
+		PreventPostDestroyRefresh: true,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccKubernetesClustersDataSourceBasic(
+					testAccKubernetesClusterBasic(createClusterFixture), clusterName,
+				),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(datasourceName, "ids.#", "1"),
+					resource.TestCheckResourceAttrPair(
+						datasourceName, "ids.0",
+						"mcs_kubernetes_cluster."+clusterName, "id",
+					),
+					resource.TestCheckResourceAttr(datasourceName, "clusters.#", "1"),
+					resource.TestCheckResourceAttr(datasourceName, "clusters.0.name", clusterName),
+					resource.TestCheckResourceAttr(datasourceName, "clusters.0.status", "RUNNING"),
+					resource.TestCheckResourceAttr(datasourceName, "clusters.0.cluster_template_id", clusterTemplateID),
+					resource.TestCheckResourceAttr(datasourceName, "clusters.0.master_count", "1"),
+					// The details are not returned in the list of clusters.
+					resource.TestCheckResourceAttrPair(
+						datasourceName, "clusters.0.created_at",
+						"mcs_kubernetes_cluster."+clusterName, "created_at",
+					),
+					resource.TestCheckResourceAttrPair(
+						datasourceName, "clusters.0.api_address",
+						"mcs_kubernetes_cluster."+clusterName, "api_address",
+					),
+					resource.TestCheckResourceAttrSet(datasourceName, "clusters.0.labels.kube_tag"),
+				),
+			},
+		},
+	})
+}
+
+func testAccKubernetesClustersDataSourceBasic(clusterResource, clusterName string) string {
+	return fmt.Sprintf(`
+%s
+
+data "mcs_kubernetes_clusters" "%[2]s" {
+  name_regex          = "^${mcs_kubernetes_cluster.%[2]s.name}$"
+  status              = "RUNNING"
+  cluster_template_id = "%[3]s"
+}
+`, clusterResource, clusterName, clusterTemplateID)
+}
+
+func TestClustersFilter(t *testing.T) {
+	newCluster := func(id, name, status, templateID string, labels map[string]string) cluster {
+		return cluster{
+			UUID:              id,
+			Name:              name,
+			NewStatus:         clusterStatus(status),
+			ClusterTemplateID: templateID,
+			Labels:            labels,
+		}
+	}
+	details := map[string]cluster{
+		"1": newCluster("1", "prod-a", "RUNNING", "t1", map[string]string{"env": "prod", "team": "a"}),
+		"2": newCluster("2", "prod-b", "SHUTOFF", "t1", map[string]string{"env": "prod", "team": "b"}),
+		"3": newCluster("3", "dev-a", "RUNNING", "t2", map[string]string{"env": "dev", "team": "a"}),
+		"4": newCluster("4", "dev-b", "RUNNING", "t2", nil),
+	}
+	// The list of clusters doesn't include the labels, and the cluster 5 is
+	// deleted after listing.
+	clusters := []cluster{
+		newCluster("1", "prod-a", "RUNNING", "t1", nil),
+		newCluster("2", "prod-b", "SHUTOFF", "t1", nil),
+		newCluster("3", "dev-a", "RUNNING", "t2", nil),
+		newCluster("4", "dev-b", "RUNNING", "t2", nil),
+		newCluster("5", "dev-c", "RUNNING", "t2", nil),
+	}
+	get := func(id string) (*cluster, error) {
+		c, ok := details[id]
+		if !ok {
+			return nil, gophercloud.ErrDefault404{}
+		}
+		return &c, nil
+	}
+	ids := func(clusters []cluster) []string {
+		ids := []string{}
+		for _, c := range clusters {
+			ids = append(ids, c.UUID)
+		}
+		return ids
+	}
+
+	tests := map[string]struct {
+		filter   clustersFilter
+		expected []string
+	}{
+		"no filters": {
+			filter:   clustersFilter{},
+			expected: []string{"1", "2", "3", "4"},
+		},
+		"name regex": {
+			filter:   clustersFilter{nameRegex: regexp.MustCompile("^prod-")},
+			expected: []string{"1", "2"},
+		},
+		"status": {
+			filter:   clustersFilter{status: "RUNNING"},
+			expected: []string{"1", "3", "4"},
+		},
+		"cluster template": {
+			filter:   clustersFilter{clusterTemplateID: "t2"},
+			expected: []string{"3", "4"},
+		},
+		"labels": {
+			filter:   clustersFilter{labels: map[string]string{"team": "a"}},
+			expected: []string{"1", "3"},
+		},
+		"all filters": {
+			filter: clustersFilter{
+				nameRegex:         regexp.MustCompile("-a$"),
+				status:            "RUNNING",
+				clusterTemplateID: "t1",
+				labels:            map[string]string{"env": "prod", "team": "a"},
+			},
+			expected: []string{"1"},
+		},
+		"nothing matches": {
+			filter:   clustersFilter{labels: map[string]string{"env": "stage"}},
+			expected: []string{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			filtered, err := tt.filter.apply(clusters, get)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, ids(filtered))
+		})
+	}
+}
@@ -63,34 +63,7 @@ func dataSourceKubernetesNodeGroup() *schema.Resource {
 				Type:     schema.TypeString,
 				Computed: true,
 			},
-			"nodes": {
-				Type:     schema.TypeList,
-				Computed: true,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"uuid": {
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-						"name": {
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-						"node_group_id": {
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-						"created_at": {
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-						"updated_at": {
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-					},
-				},
-			},
+			"nodes": nodeGroupNodesSchema(),
 			"availability_zones": {
 				Type:     schema.TypeList,
 				Computed: true,
@@ -112,7 +85,7 @@ func dataSourceKubernetesNodeGroupRead(d *schema.ResourceData, meta interface{})
 		return checkDeleted(d, err, "error retrieving mcs_kubernetes_node_group")
 	}
 
-	log.Printf("[DEBUG] Retrieved mcs_kubernetes_node_group %s: %#v", d.Id(), nodeGroup)
+	log.Printf("[DEBUG] Retrieved mcs_kubernetes_node_group %s", d.Id())
 
 	d.SetId(nodeGroup.UUID)
 	d.Set("cluster_id", nodeGroup.ClusterID)
@@ -128,10 +101,10 @@ func dataSourceKubernetesNodeGroupRead(d *schema.ResourceData, meta interface{})
 	d.Set("state", nodeGroup.State)
 	d.Set("availability_zones", nodeGroup.AvailabilityZones)
 
-	if err := d.Set("created_at", getTimestamp(&nodeGroup.CreatedAt)); err != nil {
+	if err := d.Set("created_at", getTimestamp(nodeGroup.CreatedAt)); err != nil {
 		log.Printf("[DEBUG] Unable to set mcs_kubernetes_node_group created_at: %s", err)
 	}
-	if err := d.Set("updated_at", getTimestamp(&nodeGroup.UpdatedAt)); err != nil {
+	if err := d.Set("updated_at", getTimestamp(nodeGroup.UpdatedAt)); err != nil {
 		log.Printf("[DEBUG] Unable to set mcs_kubernetes_node_group updated_at: %s", err)
 	}
 
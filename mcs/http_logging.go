@@ -0,0 +1,148 @@
+package mcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/logging"
+)
+
+const maskedValue = "***"
+
+// sensitiveKeys are JSON keys whose values are never written to the log.
+var sensitiveKeys = map[string]bool{
+	"password":                      true,
+	"root_password":                 true,
+	"registry_auth_password":        true,
+	"admin_password":                true,
+	"adminpass":                     true,
+	"secret":                        true,
+	"application_credential_secret": true,
+	"private_key":                   true,
+	"client_key":                    true,
+	"kubeconfig":                    true,
+}
+
+// requestIDHeaders are response headers carrying the ID of the request
+// on the API side, in order of preference.
+var requestIDHeaders = []string{
+	"X-Openstack-Request-Id",
+	"X-Compute-Request-Id",
+	"X-Request-Id",
+}
+
+// traceRoundTripper logs requests to MCS APIs at TRACE level with
+// sensitive values masked.
+type traceRoundTripper struct {
+	rt http.RoundTripper
+}
+
+func newTraceRoundTripper(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &traceRoundTripper{rt: rt}
+}
+
+// RoundTrip performs the request and logs it when TF_LOG is TRACE.
+func (t *traceRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if logging.LogLevel() != "TRACE" {
+		return t.rt.RoundTrip(req)
+	}
+
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	start := time.Now()
+	resp, err := t.rt.RoundTrip(req)
+	latency := time.Since(start)
+	if err != nil {
+		log.Printf("[TRACE] MCS API %s %s failed after %s: %s\nRequest body: %s",
+			req.Method, req.URL, latency, err, redactBody(reqBody, req.Header.Get("Content-Type")))
+		return resp, err
+	}
+
+	var respBody []byte
+	if resp.Body != nil {
+		respBody, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+		if err != nil {
+			return resp, err
+		}
+	}
+
+	log.Printf("[TRACE] MCS API %s %s: %d in %s, request ID %q\nRequest body: %s\nResponse body: %s",
+		req.Method, req.URL, resp.StatusCode, latency, requestID(resp.Header),
+		redactBody(reqBody, req.Header.Get("Content-Type")),
+		redactBody(respBody, resp.Header.Get("Content-Type")))
+	return resp, nil
+}
+
+func requestID(header http.Header) string {
+	for _, h := range requestIDHeaders {
+		if id := header.Get(h); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// redactBody returns body suitable for the log. Only JSON bodies are logged,
+// with values of sensitive keys masked; other content, e.g. kubeconfig, is omitted.
+func redactBody(body []byte, contentType string) string {
+	if len(body) == 0 {
+		return "<empty>"
+	}
+	if !strings.Contains(contentType, "json") {
+		return "<omitted " + contentType + " body>"
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return "<omitted malformed JSON body>"
+	}
+	redacted, err := json.Marshal(redactValue("", v))
+	if err != nil {
+		return "<omitted malformed JSON body>"
+	}
+	return string(redacted)
+}
+
+func redactValue(parent string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if isSensitiveKey(parent, key) {
+				v[key] = maskedValue
+				continue
+			}
+			v[key] = redactValue(key, value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactValue(parent, value)
+		}
+	}
+	return v
+}
+
+func isSensitiveKey(parent, key string) bool {
+	key = strings.ToLower(key)
+	if sensitiveKeys[key] {
+		return true
+	}
+	// Token authentication passes the token as {"token": {"id": "..."}}.
+	return parent == "token" && key == "id"
+}
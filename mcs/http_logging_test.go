@@ -0,0 +1,90 @@
+package mcs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	out := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(out) })
+	return &buf
+}
+
+func TestTraceRoundTripperMasksSecrets(t *testing.T) {
+	t.Setenv("TF_LOG", "TRACE")
+	buf := captureLog(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Contains(t, string(body), "s3cr3t", "request body must reach the API intact")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Openstack-Request-Id", "req-42")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"instance": {"id": "i-1", "users": [{"name": "u", "password": "r3sp0nse"}]}}`))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newTraceRoundTripper(nil)}
+	reqBody := `{"instance": {"root_password": "s3cr3t", "users": [{"name": "u", "password": "s3cr3t"}]},` +
+		`"auth": {"identity": {"token": {"id": "t0k3n"}}}}`
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/instances", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Contains(t, string(respBody), "r3sp0nse", "response body must reach the caller intact")
+
+	out := buf.String()
+	assert.Contains(t, out, "[TRACE] MCS API POST "+server.URL+"/instances: 201")
+	assert.Contains(t, out, `request ID "req-42"`)
+	assert.Contains(t, out, `"root_password":"***"`)
+	assert.Contains(t, out, `"name":"u"`)
+	assert.NotContains(t, out, "s3cr3t")
+	assert.NotContains(t, out, "r3sp0nse")
+	assert.NotContains(t, out, "t0k3n")
+}
+
+func TestTraceRoundTripperOmitsNonJSONBodies(t *testing.T) {
+	t.Setenv("TF_LOG", "TRACE")
+	buf := captureLog(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-yaml")
+		_, _ = w.Write([]byte("client-key-data: a2V5"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newTraceRoundTripper(nil)}
+	resp, err := client.Get(server.URL + "/kube_config")
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Contains(t, buf.String(), "<omitted application/x-yaml body>")
+	assert.NotContains(t, buf.String(), "a2V5")
+}
+
+func TestTraceRoundTripperSilentBelowTrace(t *testing.T) {
+	t.Setenv("TF_LOG", "DEBUG")
+	buf := captureLog(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newTraceRoundTripper(nil)}
+	resp, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.NotContains(t, buf.String(), "[TRACE]")
+}
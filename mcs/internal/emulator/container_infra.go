@@ -0,0 +1,771 @@
+package emulator
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statuses of emulated kubernetes clusters.
+const (
+	clusterProvisioning = "PROVISIONING"
+	clusterRunning      = "RUNNING"
+	clusterReconciling  = "RECONCILING"
+	clusterShutoff      = "SHUTOFF"
+	clusterDeleting     = "DELETING"
+)
+
+// Range of container-infra API microversions the emulator reports.
+const (
+	minMicroVersion = "1.1"
+	maxMicroVersion = "1.24"
+)
+
+// maxListLimit is the maximal page size of the emulated list requests.
+const maxListLimit = 1000
+
+type k8sCluster struct {
+	fields map[string]interface{}
+	lifecycle
+}
+
+type k8sNodeGroup struct {
+	fields map[string]interface{}
+	nodes  []map[string]interface{}
+}
+
+func seedClusterTemplates() []map[string]interface{} {
+	template := func(id, version string) map[string]interface{} {
+		created := now().Format(time.RFC3339)
+		return map[string]interface{}{
+			"uuid":                  id,
+			"name":                  "Kubernetes-centos-v" + version + "-mcs.1",
+			"version":               version,
+			"project_id":            ProjectID,
+			"user_id":               "emulated-user",
+			"apiserver_port":        6443,
+			"cluster_distro":        "centos",
+			"coe":                   "kubernetes",
+			"docker_storage_driver": "overlay2",
+			"docker_volume_size":    10,
+			"external_network_id":   "ext-net",
+			"flavor_id":             "Standard-2-4-40",
+			"master_flavor_id":      "Standard-2-4-40",
+			"image_id":              "centos-k8s-" + version,
+			"labels":                map[string]string{"kube_tag": "v" + version},
+			"master_lb_enabled":     true,
+			"network_driver":        "calico",
+			"public":                true,
+			"server_type":           "vm",
+			"volume_driver":         "cinder",
+			"created_at":            created,
+			"updated_at":            created,
+			"deprecated_at":         nil,
+		}
+	}
+	deprecated := template(DeprecatedClusterTemplateID, "1.19.9")
+	deprecated["deprecated_at"] = now().AddDate(0, -1, 0).Format(time.RFC3339)
+	return []map[string]interface{}{
+		template(ClusterTemplateID, "1.20.4"),
+		template(NextClusterTemplateID, "1.21.4"),
+		deprecated,
+	}
+}
+
+func (s *Server) serveContainerInfra(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r, "/container-infra/")
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			notFound(w, r)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"versions": []map[string]interface{}{{
+				"id":          "v1",
+				"status":      "CURRENT",
+				"min_version": minMicroVersion,
+				"max_version": maxMicroVersion,
+			}},
+		})
+		return
+	}
+	if parts[0] != "v1" || len(parts) < 2 {
+		notFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parts = parts[1:]
+	switch parts[0] {
+	case "clustertemplates":
+		s.serveClusterTemplates(w, r, parts[1:])
+	case "clusters":
+		s.serveClusters(w, r, parts[1:])
+	case "nodegroups":
+		s.serveNodeGroups(w, r, parts[1:])
+	default:
+		notFound(w, r)
+	}
+}
+
+func (s *Server) serveClusterTemplates(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"clustertemplates": s.templates})
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		s.createClusterTemplate(w, r)
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		// Templates are looked up by ID, name or kubernetes version.
+		for _, t := range s.templates {
+			if t["uuid"] == parts[0] || t["name"] == parts[0] || t["version"] == parts[0] {
+				writeJSON(w, http.StatusOK, t)
+				return
+			}
+		}
+		notFound(w, r)
+	case len(parts) == 1 && r.Method == http.MethodPatch:
+		s.patchClusterTemplate(w, r, parts[0])
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		s.deleteClusterTemplate(w, r, parts[0])
+	default:
+		notFound(w, r)
+	}
+}
+
+func (s *Server) createClusterTemplate(w http.ResponseWriter, r *http.Request) {
+	var fields map[string]interface{}
+	if err := decodeBody(r, &fields); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	for _, key := range []string{"name", "coe", "image_id"} {
+		if v, _ := fields[key].(string); v == "" {
+			writeError(w, http.StatusBadRequest, "%s is required", key)
+			return
+		}
+	}
+
+	created := now().Format(time.RFC3339)
+	t := map[string]interface{}{
+		"uuid":                  newID(),
+		"project_id":            ProjectID,
+		"user_id":               "emulated-user",
+		"apiserver_port":        6443,
+		"cluster_distro":        "centos",
+		"docker_storage_driver": "overlay2",
+		"docker_volume_size":    10,
+		"network_driver":        "calico",
+		"server_type":           "vm",
+		"volume_driver":         "cinder",
+		"labels":                map[string]interface{}{},
+		"master_lb_enabled":     false,
+		"floating_ip_enabled":   false,
+		"public":                false,
+		"registry_enabled":      false,
+		"tls_disabled":          false,
+		"created_at":            created,
+		"updated_at":            created,
+		"deprecated_at":         nil,
+	}
+	for key, value := range fields {
+		t[key] = value
+	}
+	setTemplateVersion(t)
+	s.templates = append(s.templates, t)
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (s *Server) patchClusterTemplate(w http.ResponseWriter, r *http.Request, id string) {
+	t := s.template(id)
+	if t == nil {
+		notFound(w, r)
+		return
+	}
+	var ops []struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}
+	if err := decodeBody(r, &ops); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	for _, op := range ops {
+		key := strings.TrimPrefix(op.Path, "/")
+		switch op.Op {
+		case "replace", "add":
+			t[key] = op.Value
+		case "remove":
+			delete(t, key)
+		default:
+			writeError(w, http.StatusBadRequest, "unsupported patch operation %q", op.Op)
+			return
+		}
+	}
+	setTemplateVersion(t)
+	t["updated_at"] = now().Format(time.RFC3339)
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (s *Server) deleteClusterTemplate(w http.ResponseWriter, r *http.Request, id string) {
+	for _, c := range s.clusters {
+		if c.fields["cluster_template_id"] == id {
+			writeError(w, http.StatusBadRequest, "cluster template %s is referenced by cluster %s", id, c.fields["uuid"])
+			return
+		}
+	}
+	for i, t := range s.templates {
+		if t["uuid"] == id {
+			s.templates = append(s.templates[:i], s.templates[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	notFound(w, r)
+}
+
+// setTemplateVersion sets the kubernetes version of the template from its kube_tag label.
+func setTemplateVersion(t map[string]interface{}) {
+	labels, _ := t["labels"].(map[string]interface{})
+	tag, _ := labels["kube_tag"].(string)
+	t["version"] = strings.TrimPrefix(tag, "v")
+}
+
+func (s *Server) serveClusters(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			s.listClusters(w, r)
+		case http.MethodPost:
+			s.createCluster(w, r)
+		default:
+			notFound(w, r)
+		}
+		return
+	}
+
+	c := s.cluster(parts[0])
+	if c == nil {
+		notFound(w, r)
+		return
+	}
+	id := c.fields["uuid"].(string)
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		if c.read() == deleted {
+			delete(s.clusters, id)
+			notFound(w, r)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.renderCluster(c))
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		c.begin(clusterDeleting, deleted)
+		for ngID, ng := range s.nodeGroups {
+			if ng.fields["cluster_id"] == id {
+				delete(s.nodeGroups, ngID)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 1 && r.Method == http.MethodPatch:
+		s.patchCluster(w, r, c)
+	case len(parts) == 2 && parts[1] == "actions" && r.Method == http.MethodPost:
+		s.clusterAction(w, r, c)
+	case len(parts) == 3 && parts[1] == "actions" && parts[2] == "upgrade" && r.Method == http.MethodPatch:
+		var opts struct {
+			ClusterTemplateID string `json:"cluster_template_id"`
+		}
+		if err := decodeBody(r, &opts); err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		if s.template(opts.ClusterTemplateID) == nil {
+			writeError(w, http.StatusBadRequest, "cluster template %s is not found", opts.ClusterTemplateID)
+			return
+		}
+		c.fields["cluster_template_id"] = opts.ClusterTemplateID
+		c.begin(clusterReconciling, clusterRunning)
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{"uuid": id})
+	case len(parts) == 2 && parts[1] == "nodegroups" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"nodegroups": s.clusterNodeGroups(id)})
+	case len(parts) == 2 && parts[1] == "kube_config" && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "application/x-yaml")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, kubeConfig(c.fields))
+	default:
+		notFound(w, r)
+	}
+}
+
+// listClusters renders a page of clusters ordered by creation time. As magnum
+// does, the page size is set by the limit parameter, the page starts after
+// the marker cluster and the link to the next page is returned if there is one.
+func (s *Server) listClusters(w http.ResponseWriter, r *http.Request) {
+	ids := make([]string, 0, len(s.clusters))
+	for id := range s.clusters {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := s.clusters[ids[i]].fields, s.clusters[ids[j]].fields
+		if a["created_at"] != b["created_at"] {
+			return a["created_at"].(string) < b["created_at"].(string)
+		}
+		return ids[i] < ids[j]
+	})
+
+	query := r.URL.Query()
+	if marker := query.Get("marker"); marker != "" {
+		i := 0
+		for i < len(ids) && ids[i] != marker {
+			i++
+		}
+		if i == len(ids) {
+			writeError(w, http.StatusNotFound, "marker %s is not found", marker)
+			return
+		}
+		ids = ids[i+1:]
+	}
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 || limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	body := map[string]interface{}{}
+	if len(ids) > limit {
+		ids = ids[:limit]
+		body["next"] = fmt.Sprintf("%sclusters?limit=%d&marker=%s", s.containerInfraEndpoint(), limit, ids[limit-1])
+	}
+	list := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, s.renderClusterSummary(s.clusters[id]))
+	}
+	body["clusters"] = list
+	writeJSON(w, http.StatusOK, body)
+}
+
+// clusterSystemLabel is the label the service sets on all clusters.
+const clusterSystemLabel = "mcs.mail.ru/cluster-template"
+
+// immutableLabels are cluster labels which can't be changed by a patch.
+var immutableLabels = []string{"calico_ipv4pool", "container_infra_prefix", "fixed_network", "fixed_subnet", "kube_tag"}
+
+func (s *Server) patchCluster(w http.ResponseWriter, r *http.Request, c *k8sCluster) {
+	if c.status != clusterRunning {
+		writeError(w, http.StatusConflict, "cluster %s is %s", c.fields["uuid"], c.status)
+		return
+	}
+	var ops []struct {
+		Op    string                 `json:"op"`
+		Path  string                 `json:"path"`
+		Value map[string]interface{} `json:"value"`
+	}
+	if err := decodeBody(r, &ops); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	for _, op := range ops {
+		if op.Op != "replace" || op.Path != "/labels" {
+			writeError(w, http.StatusBadRequest, "unsupported patch operation %s %s", op.Op, op.Path)
+			return
+		}
+		labels, _ := c.fields["labels"].(map[string]interface{})
+		for _, name := range immutableLabels {
+			if labels[name] != op.Value[name] {
+				writeError(w, http.StatusBadRequest, "label %s can't be changed", name)
+				return
+			}
+		}
+		c.fields["labels"] = op.Value
+	}
+	c.begin(clusterReconciling, clusterRunning)
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{"uuid": c.fields["uuid"]})
+}
+
+// cluster looks up a cluster by its ID or name, as magnum does.
+func (s *Server) cluster(id string) *k8sCluster {
+	if c, ok := s.clusters[id]; ok {
+		return c
+	}
+	for _, c := range s.clusters {
+		if c.fields["name"] == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *Server) template(id string) map[string]interface{} {
+	for _, t := range s.templates {
+		if t["uuid"] == id {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *Server) createCluster(w http.ResponseWriter, r *http.Request) {
+	var fields map[string]interface{}
+	if err := decodeBody(r, &fields); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	for _, key := range []string{"name", "cluster_template_id", "network_id", "subnet_id"} {
+		if v, _ := fields[key].(string); v == "" {
+			writeError(w, http.StatusBadRequest, "%s is required", key)
+			return
+		}
+	}
+	templateID := fields["cluster_template_id"].(string)
+	template := s.template(templateID)
+	if template == nil {
+		writeError(w, http.StatusBadRequest, "cluster template %s is not found", templateID)
+		return
+	}
+	if _, ok := fields["master_count"]; !ok {
+		fields["master_count"] = float64(1)
+	}
+	// As magnum does, the cluster inherits labels of its template which are
+	// not set on the cluster itself.
+	labels := map[string]interface{}{}
+	switch templateLabels := template["labels"].(type) {
+	case map[string]string:
+		for key, value := range templateLabels {
+			labels[key] = value
+		}
+	case map[string]interface{}:
+		for key, value := range templateLabels {
+			labels[key] = value
+		}
+	}
+	clusterLabels, _ := fields["labels"].(map[string]interface{})
+	for key, value := range clusterLabels {
+		labels[key] = value
+	}
+	labels[clusterSystemLabel] = templateID
+	fields["labels"] = labels
+	if _, ok := fields["insecure_registries"]; !ok {
+		fields["insecure_registries"] = []string{}
+	}
+
+	id := newID()
+	created := now().Format(time.RFC3339)
+	fields["uuid"] = id
+	fields["project_id"] = ProjectID
+	fields["user_id"] = "emulated-user"
+	fields["stack_id"] = newID()
+	fields["api_address"] = "https://10.0.0.10:6443"
+	fields["master_addresses"] = []string{"10.0.0.10"}
+	fields["created_at"] = created
+	fields["updated_at"] = created
+
+	c := &k8sCluster{fields: fields}
+	c.begin(clusterProvisioning, clusterRunning)
+	s.clusters[id] = c
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{"uuid": id})
+}
+
+func (s *Server) clusterAction(w http.ResponseWriter, r *http.Request, c *k8sCluster) {
+	var opts struct {
+		Action  string      `json:"action"`
+		Payload interface{} `json:"payload"`
+	}
+	if err := decodeBody(r, &opts); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	payload, _ := opts.Payload.(map[string]interface{})
+	switch opts.Action {
+	case "resize_masters":
+		c.fields["master_flavor_id"] = payload["flavor"]
+		c.begin(clusterReconciling, clusterRunning)
+	case "scale_masters":
+		count, _ := payload["master_count"].(float64)
+		if count != 1 && count != 3 && count != 5 || count < float64(intField(c.fields, "master_count")) {
+			writeError(w, http.StatusBadRequest, "master_count %v is not allowed", payload["master_count"])
+			return
+		}
+		addresses := make([]string, int(count))
+		for i := range addresses {
+			addresses[i] = fmt.Sprintf("10.0.0.%d", 10+i)
+		}
+		c.fields["master_count"] = count
+		c.fields["master_addresses"] = addresses
+		c.begin(clusterReconciling, clusterRunning)
+	case "batch_add_ng":
+		groups, _ := opts.Payload.([]interface{})
+		for _, g := range groups {
+			fields, _ := g.(map[string]interface{})
+			if fields == nil {
+				writeError(w, http.StatusBadRequest, "node group must be an object")
+				return
+			}
+			fields["cluster_id"] = c.fields["uuid"]
+			if err := s.addNodeGroup(fields); err != nil {
+				writeError(w, http.StatusBadRequest, "%s", err)
+				return
+			}
+		}
+		c.begin(clusterReconciling, clusterRunning)
+	case "turn_off_cluster":
+		c.begin(clusterRunning, clusterShutoff)
+	case "turn_on_cluster":
+		c.begin(clusterShutoff, clusterRunning)
+	default:
+		writeError(w, http.StatusBadRequest, "unknown action %q", opts.Action)
+		return
+	}
+	c.fields["updated_at"] = now().Format(time.RFC3339)
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{"uuid": c.fields["uuid"]})
+}
+
+func (s *Server) renderCluster(c *k8sCluster) map[string]interface{} {
+	body := copyFields(c.fields)
+	body["new_status"] = c.status
+	body["status_reason"] = ""
+	return body
+}
+
+// clusterSummaryFields are the cluster fields magnum returns in the list of
+// clusters, the other ones are only returned for a single cluster.
+var clusterSummaryFields = []string{
+	"uuid", "name", "cluster_template_id", "keypair", "master_count", "node_count", "stack_id", "status",
+}
+
+func (s *Server) renderClusterSummary(c *k8sCluster) map[string]interface{} {
+	body := map[string]interface{}{"new_status": c.status}
+	for _, field := range clusterSummaryFields {
+		if v, ok := c.fields[field]; ok {
+			body[field] = v
+		}
+	}
+	return body
+}
+
+// clusterNodeGroups renders a summary of the cluster node groups ordered by
+// creation time. As in magnum, the summary doesn't include the nodes.
+func (s *Server) clusterNodeGroups(clusterID string) []map[string]interface{} {
+	groups := []map[string]interface{}{}
+	for _, ng := range s.nodeGroups {
+		if ng.fields["cluster_id"] == clusterID {
+			groups = append(groups, copyFields(ng.fields))
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		ci, cj := groups[i]["created_at"].(string), groups[j]["created_at"].(string)
+		if ci != cj {
+			return ci < cj
+		}
+		return groups[i]["name"].(string) < groups[j]["name"].(string)
+	})
+	return groups
+}
+
+func (s *Server) serveNodeGroups(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodPost {
+			notFound(w, r)
+			return
+		}
+		s.createNodeGroup(w, r)
+		return
+	}
+
+	ng, ok := s.nodeGroups[parts[0]]
+	if !ok {
+		notFound(w, r)
+		return
+	}
+	cluster := s.clusters[ng.fields["cluster_id"].(string)]
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, renderNodeGroup(ng))
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		delete(s.nodeGroups, parts[0])
+		cluster.begin(clusterReconciling, clusterRunning)
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 1 && r.Method == http.MethodPatch:
+		var ops []struct {
+			Op    string      `json:"op"`
+			Path  string      `json:"path"`
+			Value interface{} `json:"value"`
+		}
+		if err := decodeBody(r, &ops); err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		for _, op := range ops {
+			if op.Op != "replace" {
+				writeError(w, http.StatusBadRequest, "unsupported patch operation %q", op.Op)
+				return
+			}
+			key := strings.TrimPrefix(op.Path, "/")
+			value := op.Value
+			// Booleans may be sent as strings.
+			if str, ok := value.(string); ok && key == "autoscaling_enabled" {
+				value, _ = strconv.ParseBool(str)
+			}
+			if labels, ok := value.([]interface{}); ok && key == "labels" {
+				value = withNodeGroupSystemLabel(labels, ng.fields["name"])
+			}
+			ng.fields[key] = value
+		}
+		ng.resize(intField(ng.fields, "node_count"))
+		cluster.begin(clusterReconciling, clusterRunning)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"uuid": parts[0]})
+	case len(parts) == 3 && parts[1] == "actions" && parts[2] == "scale" && r.Method == http.MethodPatch:
+		var opts struct {
+			Delta         int      `json:"delta"`
+			NodesToRemove []string `json:"nodes_to_remove"`
+		}
+		if err := decodeBody(r, &opts); err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		count := intField(ng.fields, "node_count") + opts.Delta
+		if count < 1 {
+			writeError(w, http.StatusBadRequest, "node_count must be greater than 0, got %d", count)
+			return
+		}
+		if len(opts.NodesToRemove) > 0 && len(opts.NodesToRemove) > -opts.Delta {
+			writeError(w, http.StatusBadRequest, "%d nodes can't be removed when scaling by %d",
+				len(opts.NodesToRemove), opts.Delta)
+			return
+		}
+		if err := ng.remove(opts.NodesToRemove); err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		ng.fields["node_count"] = float64(count)
+		ng.resize(count)
+		cluster.begin(clusterReconciling, clusterRunning)
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{"uuid": parts[0]})
+	default:
+		notFound(w, r)
+	}
+}
+
+func (s *Server) createNodeGroup(w http.ResponseWriter, r *http.Request) {
+	var fields map[string]interface{}
+	if err := decodeBody(r, &fields); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	clusterID, _ := fields["cluster_id"].(string)
+	cluster, ok := s.clusters[clusterID]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "cluster %q is not found", clusterID)
+		return
+	}
+	if err := s.addNodeGroup(fields); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	cluster.begin(clusterReconciling, clusterRunning)
+	writeJSON(w, http.StatusAccepted, renderNodeGroup(s.nodeGroups[fields["uuid"].(string)]))
+}
+
+// addNodeGroup adds a node group with the fields to its cluster.
+func (s *Server) addNodeGroup(fields map[string]interface{}) error {
+	for _, ng := range s.nodeGroups {
+		if ng.fields["cluster_id"] == fields["cluster_id"] && ng.fields["name"] == fields["name"] {
+			return fmt.Errorf("node group %q already exists", fields["name"])
+		}
+	}
+	if intField(fields, "node_count") < 1 {
+		fields["node_count"] = float64(1)
+	}
+	if _, ok := fields["availability_zones"]; !ok {
+		fields["availability_zones"] = []string{}
+	}
+	labels, _ := fields["labels"].([]interface{})
+	fields["labels"] = withNodeGroupSystemLabel(labels, fields["name"])
+
+	id := newID()
+	created := now().Format(time.RFC3339)
+	fields["uuid"] = id
+	fields["state"] = clusterRunning
+	fields["created_at"] = created
+	fields["updated_at"] = created
+
+	ng := &k8sNodeGroup{fields: fields}
+	ng.resize(intField(fields, "node_count"))
+	s.nodeGroups[id] = ng
+	return nil
+}
+
+// withNodeGroupSystemLabel adds the label the service sets on all node groups.
+func withNodeGroupSystemLabel(labels []interface{}, name interface{}) []interface{} {
+	return append(labels, map[string]interface{}{"key": "mcs.mail.ru/node-group", "value": name})
+}
+
+// resize adds or removes nodes of the node group to match count.
+func (ng *k8sNodeGroup) resize(count int) {
+	for len(ng.nodes) < count {
+		ng.nodes = append(ng.nodes, map[string]interface{}{
+			"name":          fmt.Sprintf("%s-%d", ng.fields["name"], len(ng.nodes)),
+			"uuid":          newID(),
+			"node_group_id": ng.fields["uuid"],
+			"created_at":    now().Format(time.RFC3339),
+		})
+	}
+	if len(ng.nodes) > count {
+		ng.nodes = ng.nodes[:count]
+	}
+}
+
+// remove removes the nodes with the UUIDs from the node group.
+func (ng *k8sNodeGroup) remove(uuids []string) error {
+	for _, id := range uuids {
+		i := 0
+		for i < len(ng.nodes) && ng.nodes[i]["uuid"] != id {
+			i++
+		}
+		if i == len(ng.nodes) {
+			return fmt.Errorf("node %q is not found in node group %q", id, ng.fields["uuid"])
+		}
+		ng.nodes = append(ng.nodes[:i], ng.nodes[i+1:]...)
+	}
+	return nil
+}
+
+func renderNodeGroup(ng *k8sNodeGroup) map[string]interface{} {
+	body := copyFields(ng.fields)
+	body["nodes"] = ng.nodes
+	return body
+}
+
+// kubeConfig returns an admin kubeconfig of the cluster.
+func kubeConfig(fields map[string]interface{}) string {
+	pem := func(kind, content string) string {
+		body := base64.StdEncoding.EncodeToString([]byte(content))
+		return base64.StdEncoding.EncodeToString([]byte(
+			"-----BEGIN " + kind + "-----\n" + body + "\n-----END " + kind + "-----\n"))
+	}
+	name := fields["name"]
+	return fmt.Sprintf(`apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: %s
+    server: %s
+  name: %s
+contexts:
+- context:
+    cluster: %[3]s
+    user: admin_%[3]s
+  name: default/%[3]s
+current-context: default/%[3]s
+kind: Config
+preferences: {}
+users:
+- name: admin_%[3]s
+  user:
+    client-certificate-data: %s
+    client-key-data: %s
+`, pem("CERTIFICATE", "emulated ca"), fields["api_address"], name,
+		pem("CERTIFICATE", "emulated client"), pem("RSA PRIVATE KEY", "emulated key"))
+}
@@ -0,0 +1,642 @@
+package emulator
+
+import (
+	"net/http"
+	"sort"
+)
+
+// Statuses of emulated database instances and clusters.
+const (
+	instanceBuild              = "BUILD"
+	instanceActive             = "ACTIVE"
+	instanceResize             = "RESIZE"
+	instanceDetach             = "DETACH"
+	instanceShutdown           = "SHUTDOWN"
+	instanceCapabilityApplying = "CAPABILITY_APPLYING"
+
+	dbClusterBuilding           = "BUILDING"
+	dbClusterActive             = "CLUSTER_ACTIVE"
+	dbClusterResizing           = "RESIZING_CLUSTER"
+	dbClusterUpdating           = "UPDATING_CLUSTER"
+	dbClusterGrowing            = "GROWING_CLUSTER"
+	dbClusterShrinking          = "SHRINKING_CLUSTER"
+	dbClusterDeleting           = "DELETING"
+	dbClusterCapabilityApplying = "CAPABILITY_APPLYING"
+)
+
+// dbTimeFormat is the format of timestamps in dbaas responses.
+const dbTimeFormat = "2006-01-02T15:04:05"
+
+// dbms holds what database instances and clusters have in common.
+type dbms struct {
+	lifecycle
+	capabilities []interface{}
+	users        map[string]map[string]interface{}
+	databases    map[string]map[string]interface{}
+	rootPassword string
+	rootEnabled  bool
+}
+
+func newDBMS(status, target string) dbms {
+	d := dbms{
+		users:     make(map[string]map[string]interface{}),
+		databases: make(map[string]map[string]interface{}),
+	}
+	d.begin(status, target)
+	return d
+}
+
+type dbInstance struct {
+	dbms
+	fields map[string]interface{}
+}
+
+type dbCluster struct {
+	dbms
+	fields    map[string]interface{}
+	instances []map[string]interface{}
+}
+
+func (s *Server) serveDatabase(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r, "/database/v1.0/"+ProjectID+"/")
+	if len(parts) == 0 {
+		notFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch parts[0] {
+	case "instances":
+		s.serveInstances(w, r, parts[1:])
+	case "clusters":
+		s.serveDBClusters(w, r, parts[1:])
+	default:
+		notFound(w, r)
+	}
+}
+
+func (s *Server) serveInstances(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodPost {
+			notFound(w, r)
+			return
+		}
+		s.createInstance(w, r)
+		return
+	}
+
+	id := parts[0]
+	inst, ok := s.instances[id]
+	if !ok {
+		// Capabilities of cluster instances are requested by instance ID.
+		if len(parts) == 2 && parts[1] == "capabilities" && r.Method == http.MethodGet {
+			if c := s.clusterOfInstance(id); c != nil {
+				writeCapabilities(w, &c.dbms)
+				return
+			}
+		}
+		notFound(w, r)
+		return
+	}
+	if len(parts) == 2 && parts[1] == "action" && r.Method == http.MethodPost {
+		s.instanceAction(w, r, inst)
+		return
+	}
+	if len(parts) > 1 {
+		s.serveDBMS(w, r, &inst.dbms, parts[1:])
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if inst.read() == deleted {
+			delete(s.instances, id)
+			notFound(w, r)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"instance": s.renderInstance(inst)})
+	case http.MethodDelete:
+		inst.begin(instanceShutdown, deleted)
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodPatch:
+		var opts struct {
+			Instance map[string]interface{} `json:"instance"`
+		}
+		if err := decodeBody(r, &opts); err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		switch {
+		case opts.Instance["replica_of"] != nil:
+			delete(inst.fields, "replica_of")
+			inst.begin(instanceDetach, instanceActive)
+		case opts.Instance["wal_volume"] != nil:
+			wal, _ := inst.fields["wal_volume"].(map[string]interface{})
+			if wal == nil {
+				writeError(w, http.StatusBadRequest, "instance %s has no wal volume", id)
+				return
+			}
+			for k, v := range opts.Instance["wal_volume"].(map[string]interface{}) {
+				wal[k] = v
+			}
+			inst.begin(instanceBuild, instanceActive)
+		default:
+			for k, v := range opts.Instance {
+				inst.fields[k] = v
+			}
+			inst.begin(instanceBuild, instanceActive)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodPut:
+		var opts struct {
+			Instance map[string]interface{} `json:"instance"`
+		}
+		if err := decodeBody(r, &opts); err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		// An instance without configuration detaches its configuration group.
+		inst.fields["configuration"] = opts.Instance["configuration"]
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		notFound(w, r)
+	}
+}
+
+func (s *Server) createInstance(w http.ResponseWriter, r *http.Request) {
+	var opts struct {
+		Instance map[string]interface{} `json:"instance"`
+	}
+	if err := decodeBody(r, &opts); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	fields := opts.Instance
+	for _, key := range []string{"name", "datastore", "volume", "nics"} {
+		if fields[key] == nil {
+			writeError(w, http.StatusBadRequest, "instance.%s is required", key)
+			return
+		}
+	}
+	if replicaOf, ok := fields["replica_of"].(string); ok {
+		if _, ok := s.instances[replicaOf]; !ok {
+			writeError(w, http.StatusBadRequest, "instance %s to replicate is not found", replicaOf)
+			return
+		}
+	}
+
+	id := newID()
+	created := now().Format(dbTimeFormat)
+	fields["id"] = id
+	fields["created"] = created
+	fields["updated"] = created
+	setVolumeID(fields["volume"])
+	setVolumeID(fields["wal_volume"])
+
+	inst := &dbInstance{dbms: newDBMS(instanceBuild, instanceActive), fields: fields}
+	inst.capabilities, _ = fields["capabilities"].([]interface{})
+	delete(fields, "capabilities")
+	s.instances[id] = inst
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"instance": map[string]interface{}{"id": id, "status": inst.status},
+	})
+}
+
+func (s *Server) renderInstance(inst *dbInstance) map[string]interface{} {
+	body := copyFields(inst.fields)
+	body["status"] = inst.status
+	body["flavor"] = map[string]interface{}{"id": inst.fields["flavorRef"]}
+	body["region"] = Region
+	body["ip"] = []string{"10.0.1.10"}
+	delete(body, "flavorRef")
+	delete(body, "nics")
+	delete(body, "configuration")
+	if config, ok := inst.fields["configuration"].(string); ok && config != "" {
+		body["configuration_id"] = config
+		body["configuration"] = map[string]interface{}{"id": config}
+	}
+	if replicaOf, ok := inst.fields["replica_of"].(string); ok {
+		body["replica_of"] = map[string]interface{}{"id": replicaOf}
+	}
+	return body
+}
+
+// setVolumeID assigns an ID and usage to a volume of a request.
+func setVolumeID(v interface{}) {
+	if volume, ok := v.(map[string]interface{}); ok {
+		volume["volume_id"] = newID()
+		volume["used"] = 0.1
+	}
+}
+
+func (s *Server) clusterOfInstance(id string) *dbCluster {
+	for _, c := range s.dbClusters {
+		for _, inst := range c.instances {
+			if inst["id"] == id {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) instanceAction(w http.ResponseWriter, r *http.Request, inst *dbInstance) {
+	var opts map[string]interface{}
+	if err := decodeBody(r, &opts); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	switch {
+	case opts["resize"] != nil:
+		body, _ := opts["resize"].(map[string]interface{})
+		if flavor, ok := body["flavorRef"].(string); ok {
+			inst.fields["flavorRef"] = flavor
+		}
+		if volume, ok := body["volume"].(map[string]interface{}); ok {
+			resizeVolume(inst.fields, volume)
+		}
+		inst.begin(instanceResize, instanceActive)
+	case opts["apply_capability"] != nil:
+		body, _ := opts["apply_capability"].(map[string]interface{})
+		inst.capabilities, _ = body["capabilities"].([]interface{})
+		inst.begin(instanceCapabilityApplying, instanceActive)
+	default:
+		writeError(w, http.StatusBadRequest, "unknown action")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// serveDBMS serves the API common to database instances and clusters.
+func (s *Server) serveDBMS(w http.ResponseWriter, r *http.Request, d *dbms, parts []string) {
+	switch {
+	case len(parts) == 1 && parts[0] == "capabilities" && r.Method == http.MethodGet:
+		writeCapabilities(w, d)
+	case len(parts) == 1 && parts[0] == "root":
+		switch r.Method {
+		case http.MethodPost:
+			var opts struct {
+				Password string `json:"password"`
+			}
+			_ = decodeBody(r, &opts)
+			if opts.Password == "" {
+				opts.Password = newID()
+			}
+			d.rootEnabled, d.rootPassword = true, opts.Password
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"user": map[string]interface{}{"name": "root", "password": d.rootPassword},
+			})
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, map[string]interface{}{"rootEnabled": d.rootEnabled})
+		case http.MethodDelete:
+			d.rootEnabled, d.rootPassword = false, ""
+			w.WriteHeader(http.StatusOK)
+		default:
+			notFound(w, r)
+		}
+	case parts[0] == "users":
+		s.serveUsers(w, r, d, parts[1:])
+	case parts[0] == "databases":
+		s.serveDatabases(w, r, d, parts[1:])
+	default:
+		notFound(w, r)
+	}
+}
+
+func resizeVolume(fields map[string]interface{}, opts map[string]interface{}) {
+	key := "volume"
+	if opts["kind"] == "wal" {
+		key = "wal_volume"
+	}
+	if volume, ok := fields[key].(map[string]interface{}); ok {
+		volume["size"] = opts["size"]
+	}
+}
+
+func writeCapabilities(w http.ResponseWriter, d *dbms) {
+	capabilities := make([]map[string]interface{}, 0, len(d.capabilities))
+	for _, c := range d.capabilities {
+		capability, _ := c.(map[string]interface{})
+		capabilities = append(capabilities, map[string]interface{}{
+			"name":   capability["name"],
+			"params": capability["params"],
+			"status": instanceActive,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"capabilities": capabilities})
+}
+
+func (s *Server) serveUsers(w http.ResponseWriter, r *http.Request, d *dbms, parts []string) {
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		names := make([]string, 0, len(d.users))
+		for name := range d.users {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		list := make([]map[string]interface{}, 0, len(names))
+		for _, name := range names {
+			user := copyFields(d.users[name])
+			delete(user, "password")
+			list = append(list, user)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"users": list, "links": []interface{}{}})
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		var opts struct {
+			Users []map[string]interface{} `json:"users"`
+		}
+		if err := decodeBody(r, &opts); err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		for _, user := range opts.Users {
+			name, _ := user["name"].(string)
+			if _, ok := d.users[name]; ok || name == "" {
+				writeError(w, http.StatusBadRequest, "user %q already exists or has no name", name)
+				return
+			}
+			if user["databases"] == nil {
+				user["databases"] = []interface{}{}
+			}
+			d.users[name] = user
+		}
+		w.WriteHeader(http.StatusAccepted)
+	case len(parts) >= 1:
+		user, ok := d.users[parts[0]]
+		if !ok {
+			notFound(w, r)
+			return
+		}
+		s.serveUser(w, r, d, user, parts[1:])
+	default:
+		notFound(w, r)
+	}
+}
+
+func (s *Server) serveUser(w http.ResponseWriter, r *http.Request, d *dbms, user map[string]interface{}, parts []string) {
+	name := user["name"].(string)
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodDelete:
+		delete(d.users, name)
+		w.WriteHeader(http.StatusAccepted)
+	case len(parts) == 0 && r.Method == http.MethodPut:
+		var opts struct {
+			User map[string]interface{} `json:"user"`
+		}
+		if err := decodeBody(r, &opts); err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		for k, v := range opts.User {
+			user[k] = v
+		}
+		delete(d.users, name)
+		d.users[user["name"].(string)] = user
+		w.WriteHeader(http.StatusAccepted)
+	case len(parts) == 1 && parts[0] == "databases" && r.Method == http.MethodPut:
+		var opts struct {
+			Databases []interface{} `json:"databases"`
+		}
+		if err := decodeBody(r, &opts); err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		// The list of databases the user has access to is replaced.
+		user["databases"] = opts.Databases
+		w.WriteHeader(http.StatusAccepted)
+	case len(parts) == 2 && parts[0] == "databases" && r.Method == http.MethodDelete:
+		databases, _ := user["databases"].([]interface{})
+		kept := make([]interface{}, 0, len(databases))
+		for _, db := range databases {
+			if m, _ := db.(map[string]interface{}); m["name"] != parts[1] {
+				kept = append(kept, db)
+			}
+		}
+		user["databases"] = kept
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		notFound(w, r)
+	}
+}
+
+func (s *Server) serveDatabases(w http.ResponseWriter, r *http.Request, d *dbms, parts []string) {
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		names := make([]string, 0, len(d.databases))
+		for name := range d.databases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		list := make([]map[string]interface{}, 0, len(names))
+		for _, name := range names {
+			list = append(list, d.databases[name])
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"databases": list, "links": []interface{}{}})
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		var opts struct {
+			Databases []map[string]interface{} `json:"databases"`
+		}
+		if err := decodeBody(r, &opts); err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		for _, db := range opts.Databases {
+			name, _ := db["name"].(string)
+			if _, ok := d.databases[name]; ok || name == "" {
+				writeError(w, http.StatusBadRequest, "database %q already exists or has no name", name)
+				return
+			}
+			d.databases[name] = db
+		}
+		w.WriteHeader(http.StatusAccepted)
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if _, ok := d.databases[parts[0]]; !ok {
+			notFound(w, r)
+			return
+		}
+		delete(d.databases, parts[0])
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		notFound(w, r)
+	}
+}
+
+func (s *Server) serveDBClusters(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodPost {
+			notFound(w, r)
+			return
+		}
+		s.createDBCluster(w, r)
+		return
+	}
+
+	id := parts[0]
+	c, ok := s.dbClusters[id]
+	if !ok {
+		notFound(w, r)
+		return
+	}
+	if len(parts) > 1 {
+		s.serveDBMS(w, r, &c.dbms, parts[1:])
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if c.read() == deleted {
+			delete(s.dbClusters, id)
+			notFound(w, r)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"cluster": renderDBCluster(c)})
+	case http.MethodDelete:
+		c.begin(dbClusterDeleting, deleted)
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodPatch:
+		var opts struct {
+			Cluster map[string]interface{} `json:"cluster"`
+		}
+		if err := decodeBody(r, &opts); err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		for k, v := range opts.Cluster {
+			if wal, ok := v.(map[string]interface{}); ok && k == "wal_volume" {
+				c.fields["wal_autoresize_enabled"] = wal["autoresize_enabled"]
+				c.fields["wal_autoresize_max_size"] = wal["autoresize_max_size"]
+				continue
+			}
+			c.fields[k] = v
+		}
+		c.begin(dbClusterUpdating, dbClusterActive)
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodPost:
+		s.dbClusterAction(w, r, c)
+	default:
+		notFound(w, r)
+	}
+}
+
+func (s *Server) createDBCluster(w http.ResponseWriter, r *http.Request) {
+	var opts struct {
+		Cluster map[string]interface{} `json:"cluster"`
+	}
+	if err := decodeBody(r, &opts); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	fields := opts.Cluster
+	requested, _ := fields["instances"].([]interface{})
+	if fields["name"] == nil || fields["datastore"] == nil || len(requested) == 0 {
+		writeError(w, http.StatusBadRequest, "cluster name, datastore and instances are required")
+		return
+	}
+
+	id := newID()
+	created := now().Format(dbTimeFormat)
+	fields["id"] = id
+	fields["created"] = created
+	fields["updated"] = created
+
+	c := &dbCluster{dbms: newDBMS(dbClusterBuilding, dbClusterActive), fields: fields}
+	c.capabilities, _ = fields["capabilities"].([]interface{})
+	delete(fields, "capabilities")
+	delete(fields, "instances")
+	for _, inst := range requested {
+		c.addInstance(inst.(map[string]interface{}))
+	}
+	s.dbClusters[id] = c
+	writeJSON(w, http.StatusOK, map[string]interface{}{"cluster": map[string]interface{}{"id": id}})
+}
+
+func (c *dbCluster) addInstance(opts map[string]interface{}) {
+	inst := map[string]interface{}{
+		"id":         newID(),
+		"name":       c.fields["name"].(string) + "-" + newID()[:8],
+		"flavor":     map[string]interface{}{"id": opts["flavorRef"]},
+		"volume":     opts["volume"],
+		"wal_volume": opts["wal_volume"],
+		"shard_id":   opts["shard_id"],
+		"type":       "member",
+		"role":       "",
+	}
+	setVolumeID(inst["volume"])
+	setVolumeID(inst["wal_volume"])
+	c.instances = append(c.instances, inst)
+}
+
+func (s *Server) dbClusterAction(w http.ResponseWriter, r *http.Request, c *dbCluster) {
+	var opts map[string]interface{}
+	if err := decodeBody(r, &opts); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	switch {
+	case opts["configuration_attach"] != nil:
+		body, _ := opts["configuration_attach"].(map[string]interface{})
+		c.fields["configuration_id"] = body["configuration_id"]
+	case opts["configuration_detach"] != nil:
+		delete(c.fields, "configuration_id")
+	case opts["resize"] != nil:
+		body, _ := opts["resize"].(map[string]interface{})
+		for _, inst := range c.instances {
+			if flavor, ok := body["flavorRef"].(string); ok {
+				inst["flavor"] = map[string]interface{}{"id": flavor}
+			}
+			if volume, ok := body["volume"].(map[string]interface{}); ok {
+				resizeVolume(inst, volume)
+			}
+		}
+		c.begin(dbClusterResizing, dbClusterActive)
+	case opts["apply_capability"] != nil:
+		body, _ := opts["apply_capability"].(map[string]interface{})
+		c.capabilities, _ = body["capabilities"].([]interface{})
+		c.begin(dbClusterCapabilityApplying, dbClusterActive)
+	case opts["grow"] != nil:
+		for _, inst := range opts["grow"].([]interface{}) {
+			c.addInstance(inst.(map[string]interface{}))
+		}
+		c.begin(dbClusterGrowing, dbClusterActive)
+	case opts["shrink"] != nil:
+		for _, shrink := range opts["shrink"].([]interface{}) {
+			id := shrink.(map[string]interface{})["id"]
+			for i, inst := range c.instances {
+				if inst["id"] == id {
+					c.instances = append(c.instances[:i], c.instances[i+1:]...)
+					break
+				}
+			}
+		}
+		c.begin(dbClusterShrinking, dbClusterActive)
+	default:
+		writeError(w, http.StatusBadRequest, "unknown action")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// renderDBCluster reports a building cluster by the status of its
+// instances and other transitions by its task.
+func renderDBCluster(c *dbCluster) map[string]interface{} {
+	body := copyFields(c.fields)
+	instanceStatus, task := instanceActive, "NONE"
+	switch c.status {
+	case dbClusterBuilding:
+		instanceStatus = instanceBuild
+	case dbClusterActive:
+	default:
+		task = c.status
+	}
+	instances := make([]map[string]interface{}, 0, len(c.instances))
+	for _, inst := range c.instances {
+		i := copyFields(inst)
+		i["status"] = instanceStatus
+		instances = append(instances, i)
+	}
+	body["instances"] = instances
+	body["task"] = map[string]interface{}{"id": 1, "name": task, "description": ""}
+	return body
+}
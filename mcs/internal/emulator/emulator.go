@@ -0,0 +1,188 @@
+// Package emulator implements an in-memory stand-in for the MCS APIs used by
+// the provider: identity, container-infra and dbaas. It keeps the resources
+// it is asked to create and moves them through the statuses the real services
+// report, so acceptance tests can run without a cloud.
+package emulator
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Region is the region of the service endpoints in the catalog.
+	Region = "RegionOne"
+	// ProjectID is the project tokens are scoped to.
+	ProjectID = "b0b4f6a3a1a84d4a9c1e0c5d8c2f6e70"
+	// ClusterTemplateID is the ID of the default cluster template.
+	ClusterTemplateID = "2b3c1d7e-7f4a-4c8e-9e36-9a1b0f4d5c21"
+	// NextClusterTemplateID is the ID of the cluster template of the next
+	// kubernetes version after the default one.
+	NextClusterTemplateID = "5e8f6a2d-3c1b-4d9a-8f7e-6b2a1c0d9e43"
+	// DeprecatedClusterTemplateID is the ID of the deprecated cluster template
+	// of the previous kubernetes version before the default one.
+	DeprecatedClusterTemplateID = "8d1e4b7c-2a6f-4e3d-b5c9-0f7a3e2d1c65"
+)
+
+// transitionReads is the number of reads for which a resource reports its
+// transitional status before it settles.
+const transitionReads = 1
+
+// deleted is a lifecycle target of resources being deleted.
+const deleted = ""
+
+// Server is a stateful emulator of the MCS APIs.
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	templates  []map[string]interface{}
+	clusters   map[string]*k8sCluster
+	nodeGroups map[string]*k8sNodeGroup
+	instances  map[string]*dbInstance
+	dbClusters map[string]*dbCluster
+}
+
+// New starts a new emulator with no resources but the default cluster templates.
+func New() *Server {
+	s := &Server{
+		templates:  seedClusterTemplates(),
+		clusters:   make(map[string]*k8sCluster),
+		nodeGroups: make(map[string]*k8sNodeGroup),
+		instances:  make(map[string]*dbInstance),
+		dbClusters: make(map[string]*dbCluster),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v3/", s.serveIdentity)
+	mux.HandleFunc("/container-infra/", s.serveContainerInfra)
+	mux.HandleFunc("/database/", s.serveDatabase)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+var (
+	shared     *Server
+	sharedOnce sync.Once
+)
+
+// Shared returns the emulator shared by all providers of the process,
+// starting it on the first call.
+func Shared() *Server {
+	sharedOnce.Do(func() {
+		shared = New()
+	})
+	return shared
+}
+
+// IdentityEndpoint is the auth URL of the emulator.
+func (s *Server) IdentityEndpoint() string {
+	return s.URL + "/v3/"
+}
+
+func (s *Server) containerInfraEndpoint() string {
+	return s.URL + "/container-infra/v1/"
+}
+
+func (s *Server) databaseEndpoint() string {
+	return s.URL + "/database/v1.0/" + ProjectID + "/"
+}
+
+// lifecycle is the status of an emulated resource. After a change the
+// resource reports a transitional status for transitionReads reads and then
+// settles on the target status.
+type lifecycle struct {
+	status  string
+	target  string
+	pending int
+}
+
+func settled(status string) lifecycle {
+	return lifecycle{status: status, target: status}
+}
+
+func (l *lifecycle) begin(status, target string) {
+	l.status, l.target, l.pending = status, target, transitionReads
+}
+
+// read advances the transition and returns the status to report.
+func (l *lifecycle) read() string {
+	if l.pending > 0 {
+		l.pending--
+	} else {
+		l.status = l.target
+	}
+	return l.status
+}
+
+// pathParts splits the request path below prefix into its segments.
+func pathParts(r *http.Request, prefix string) []string {
+	p := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+func decodeBody(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("malformed request body: %s", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if v != nil {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, format string, args ...interface{}) {
+	writeJSON(w, code, map[string]interface{}{
+		"errors": []map[string]interface{}{{
+			"status": code,
+			"title":  http.StatusText(code),
+			"detail": fmt.Sprintf(format, args...),
+		}},
+	})
+}
+
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, "%s %s is not found", r.Method, r.URL.Path)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// copyFields returns a shallow copy of resource fields to render them.
+func copyFields(fields map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		c[k] = v
+	}
+	return c
+}
+
+func intField(fields map[string]interface{}, key string) int {
+	if v, ok := fields[key].(float64); ok {
+		return int(v)
+	}
+	return 0
+}
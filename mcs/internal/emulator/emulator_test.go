@@ -0,0 +1,153 @@
+package emulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func do(t *testing.T, method, url string, body interface{}, out interface{}) int {
+	t.Helper()
+	var b []byte
+	if body != nil {
+		var err error
+		b, err = json.Marshal(body)
+		require.NoError(t, err)
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(b))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode < 300 {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
+
+func TestIssueToken(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	var token struct {
+		Token struct {
+			Project struct {
+				ID string `json:"id"`
+			} `json:"project"`
+			Catalog []struct {
+				Type      string `json:"type"`
+				Endpoints []struct {
+					URL string `json:"url"`
+				} `json:"endpoints"`
+			} `json:"catalog"`
+		} `json:"token"`
+	}
+	body := map[string]interface{}{
+		"auth": map[string]interface{}{"identity": map[string]interface{}{"methods": []string{"password"}}},
+	}
+	code := do(t, http.MethodPost, s.IdentityEndpoint()+"auth/tokens", body, &token)
+	require.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, ProjectID, token.Token.Project.ID)
+
+	endpoints := make(map[string]string)
+	for _, service := range token.Token.Catalog {
+		endpoints[service.Type] = service.Endpoints[0].URL
+	}
+	assert.Equal(t, s.containerInfraEndpoint(), endpoints["container-infra"])
+	assert.Equal(t, s.databaseEndpoint(), endpoints["database"])
+}
+
+func TestClusterLifecycle(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	var created struct {
+		UUID string `json:"uuid"`
+	}
+	code := do(t, http.MethodPost, s.containerInfraEndpoint()+"clusters", map[string]interface{}{
+		"name":                "test",
+		"cluster_template_id": ClusterTemplateID,
+		"master_flavor_id":    "Standard-2-4-40",
+		"network_id":          "network",
+		"subnet_id":           "subnet",
+		"keypair":             "keypair",
+	}, &created)
+	require.Equal(t, http.StatusAccepted, code)
+	require.NotEmpty(t, created.UUID)
+
+	url := s.containerInfraEndpoint() + "clusters/" + created.UUID
+	var cluster struct {
+		Status string `json:"new_status"`
+	}
+	for _, status := range []string{"PROVISIONING", "RUNNING", "RUNNING"} {
+		require.Equal(t, http.StatusOK, do(t, http.MethodGet, url, nil, &cluster))
+		assert.Equal(t, status, cluster.Status)
+	}
+
+	require.Equal(t, http.StatusNoContent, do(t, http.MethodDelete, url, nil, nil))
+	require.Equal(t, http.StatusOK, do(t, http.MethodGet, url, nil, &cluster))
+	assert.Equal(t, "DELETING", cluster.Status)
+	assert.Equal(t, http.StatusNotFound, do(t, http.MethodGet, url, nil, nil))
+}
+
+func TestInstanceLifecycle(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	var created struct {
+		Instance struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		} `json:"instance"`
+	}
+	code := do(t, http.MethodPost, s.databaseEndpoint()+"instances", map[string]interface{}{
+		"instance": map[string]interface{}{
+			"name":      "test",
+			"flavorRef": "Standard-2-4-40",
+			"volume":    map[string]interface{}{"size": 8},
+			"datastore": map[string]interface{}{"type": "mysql", "version": "8.0"},
+			"nics":      []map[string]interface{}{{"net-id": "network"}},
+		},
+	}, &created)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "BUILD", created.Instance.Status)
+
+	url := s.databaseEndpoint() + "instances/" + created.Instance.ID
+	for _, status := range []string{"BUILD", "ACTIVE"} {
+		require.Equal(t, http.StatusOK, do(t, http.MethodGet, url, nil, &created))
+		assert.Equal(t, status, created.Instance.Status)
+	}
+
+	code = do(t, http.MethodPost, url+"/databases", map[string]interface{}{
+		"databases": []map[string]interface{}{{"name": "db"}},
+	}, nil)
+	require.Equal(t, http.StatusAccepted, code)
+	code = do(t, http.MethodPost, url+"/users", map[string]interface{}{
+		"users": []map[string]interface{}{{"name": "user", "password": "secret", "databases": []map[string]interface{}{{"name": "db"}}}},
+	}, nil)
+	require.Equal(t, http.StatusAccepted, code)
+
+	var users struct {
+		Users []struct {
+			Name      string `json:"name"`
+			Databases []struct {
+				Name string `json:"name"`
+			} `json:"databases"`
+		} `json:"users"`
+	}
+	require.Equal(t, http.StatusOK, do(t, http.MethodGet, url+"/users", nil, &users))
+	require.Len(t, users.Users, 1)
+	assert.Equal(t, "user", users.Users[0].Name)
+	require.Len(t, users.Users[0].Databases, 1)
+	assert.Equal(t, "db", users.Users[0].Databases[0].Name)
+
+	require.Equal(t, http.StatusAccepted, do(t, http.MethodDelete, url, nil, nil))
+	require.Equal(t, http.StatusOK, do(t, http.MethodGet, url, nil, &created))
+	assert.Equal(t, "SHUTDOWN", created.Instance.Status)
+	assert.Equal(t, http.StatusNotFound, do(t, http.MethodGet, url, nil, nil))
+}
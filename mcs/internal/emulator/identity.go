@@ -0,0 +1,88 @@
+package emulator
+
+import (
+	"net/http"
+	"time"
+)
+
+// regions are the identity regions known to the emulator.
+var regions = []string{Region, "RegionAms"}
+
+func (s *Server) serveIdentity(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r, "/v3/")
+	switch {
+	case len(parts) == 2 && parts[0] == "auth" && parts[1] == "tokens" && r.Method == http.MethodPost:
+		s.issueToken(w, r)
+	case len(parts) == 1 && parts[0] == "regions" && r.Method == http.MethodGet:
+		list := make([]map[string]interface{}, 0, len(regions))
+		for _, id := range regions {
+			list = append(list, regionBody(id))
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"regions": list,
+			"links":   map[string]interface{}{"self": s.URL + r.URL.Path, "next": nil},
+		})
+	case len(parts) == 2 && parts[0] == "regions" && r.Method == http.MethodGet:
+		for _, id := range regions {
+			if id == parts[1] {
+				writeJSON(w, http.StatusOK, map[string]interface{}{"region": regionBody(id)})
+				return
+			}
+		}
+		notFound(w, r)
+	default:
+		notFound(w, r)
+	}
+}
+
+func regionBody(id string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":               id,
+		"description":      "",
+		"parent_region_id": "",
+		"links":            map[string]interface{}{},
+	}
+}
+
+// issueToken accepts any credentials and issues a project-scoped token with
+// a catalog pointing to the emulator.
+func (s *Server) issueToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Auth struct {
+			Identity struct {
+				Methods []string `json:"methods"`
+			} `json:"identity"`
+		} `json:"auth"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+
+	endpoint := func(url string) []map[string]interface{} {
+		return []map[string]interface{}{{
+			"id":        newID(),
+			"interface": "public",
+			"region":    Region,
+			"region_id": Region,
+			"url":       url,
+		}}
+	}
+	domain := map[string]interface{}{"id": "users", "name": "users"}
+
+	w.Header().Set("X-Subject-Token", newID())
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"token": map[string]interface{}{
+			"methods":    req.Auth.Identity.Methods,
+			"issued_at":  now().Format(time.RFC3339),
+			"expires_at": now().Add(24 * time.Hour).Format(time.RFC3339),
+			"project":    map[string]interface{}{"id": ProjectID, "name": "emulated", "domain": domain},
+			"user":       map[string]interface{}{"id": "emulated-user", "name": "emulated", "domain": domain},
+			"catalog": []map[string]interface{}{
+				{"id": newID(), "type": "identity", "name": "keystone", "endpoints": endpoint(s.IdentityEndpoint())},
+				{"id": newID(), "type": "container-infra", "name": "magnum", "endpoints": endpoint(s.containerInfraEndpoint())},
+				{"id": newID(), "type": "database", "name": "trove", "endpoints": endpoint(s.databaseEndpoint())},
+			},
+		},
+	})
+}
@@ -0,0 +1,182 @@
+package mcs
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/utils"
+)
+
+// Range of container-infra API microversions the provider understands.
+const (
+	minMagnumAPIMicroVersion = "1.1"
+	magnumAPIMicroVersion    = "1.24"
+)
+
+// clusterFieldsMicroVersions are mcs_kubernetes_cluster arguments that
+// require a container-infra API microversion newer than the minimum, i.e. the
+// microversion in which the API added them. An argument is sent when it is
+// changed or, if sentOn is set, when the sentOn argument is changed.
+var clusterFieldsMicroVersions = []struct {
+	field   string
+	version string
+	sentOn  string
+}{
+	{field: "insecure_registries", version: "1.23"},
+	{field: "loadbalancer_subnet_id", version: "1.21"},
+	{field: "upgrade_policy.0.max_surge", version: "1.24", sentOn: "cluster_template_id"},
+	{field: "upgrade_policy.0.max_unavailable", version: "1.24", sentOn: "cluster_template_id"},
+	{field: "upgrade_policy.0.drain_timeout", version: "1.24", sentOn: "cluster_template_id"},
+}
+
+// microVersion is a container-infra API microversion like 1.24.
+type microVersion struct {
+	major, minor int
+}
+
+func parseMicroVersion(v string) (microVersion, error) {
+	parts := strings.Split(v, ".")
+	if len(parts) != 2 {
+		return microVersion{}, fmt.Errorf("invalid microversion %q, expected <major>.<minor>", v)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return microVersion{}, fmt.Errorf("invalid microversion %q, expected <major>.<minor>", v)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return microVersion{}, fmt.Errorf("invalid microversion %q, expected <major>.<minor>", v)
+	}
+	return microVersion{major: major, minor: minor}, nil
+}
+
+func mustParseMicroVersion(v string) microVersion {
+	mv, err := parseMicroVersion(v)
+	if err != nil {
+		panic(err)
+	}
+	return mv
+}
+
+func (v microVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.major, v.minor)
+}
+
+func (v microVersion) less(o microVersion) bool {
+	if v.major != o.major {
+		return v.major < o.major
+	}
+	return v.minor < o.minor
+}
+
+// apiVersionsResult is a response of the container-infra root endpoint.
+type apiVersionsResult struct {
+	Versions []struct {
+		ID         string `json:"id"`
+		MinVersion string `json:"min_version"`
+		MaxVersion string `json:"max_version"`
+	} `json:"versions"`
+}
+
+// supportedMicroVersions requests the range of v1 microversions supported by
+// the container-infra service from its root endpoint.
+func supportedMicroVersions(client *gophercloud.ServiceClient) (microVersion, microVersion, error) {
+	root, err := utils.BaseEndpoint(client.Endpoint)
+	if err != nil {
+		return microVersion{}, microVersion{}, err
+	}
+	var result apiVersionsResult
+	_, err = client.Get(root, &result, &gophercloud.RequestOpts{OkCodes: []int{200, 300}})
+	if err != nil {
+		return microVersion{}, microVersion{}, err
+	}
+	for _, v := range result.Versions {
+		if v.ID != "v1" {
+			continue
+		}
+		min, err := parseMicroVersion(v.MinVersion)
+		if err != nil {
+			return microVersion{}, microVersion{}, err
+		}
+		max, err := parseMicroVersion(v.MaxVersion)
+		if err != nil {
+			return microVersion{}, microVersion{}, err
+		}
+		return min, max, nil
+	}
+	return microVersion{}, microVersion{}, fmt.Errorf("container-infra root endpoint %s does not list API v1", root)
+}
+
+// negotiateMicroVersion picks the container-infra API microversion to use:
+// the pinned one if set, otherwise the highest one supported by both the
+// server and the provider. The server range is unknown if discoverErr is set.
+func negotiateMicroVersion(pinned string, serverMin, serverMax microVersion, discoverErr error) (string, error) {
+	providerMin := mustParseMicroVersion(minMagnumAPIMicroVersion)
+	providerMax := mustParseMicroVersion(magnumAPIMicroVersion)
+
+	if pinned != "" {
+		v, err := parseMicroVersion(pinned)
+		if err != nil {
+			return "", fmt.Errorf("invalid container_infra_api_version: %s", err)
+		}
+		if v.less(providerMin) || providerMax.less(v) {
+			return "", fmt.Errorf("container_infra_api_version %s is not supported by the provider, expected %s to %s",
+				v, providerMin, providerMax)
+		}
+		if discoverErr == nil && (v.less(serverMin) || serverMax.less(v)) {
+			return "", fmt.Errorf("container_infra_api_version %s is not supported by the server, expected %s to %s",
+				v, serverMin, serverMax)
+		}
+		return v.String(), nil
+	}
+
+	if discoverErr != nil {
+		log.Printf("[WARN] Unable to discover container-infra API versions, using %s: %s", providerMax, discoverErr)
+		return providerMax.String(), nil
+	}
+	if serverMax.less(providerMin) || providerMax.less(serverMin) {
+		return "", fmt.Errorf("container-infra API versions %s to %s are not supported by the provider, expected %s to %s",
+			serverMin, serverMax, providerMin, providerMax)
+	}
+	if serverMax.less(providerMax) {
+		return serverMax.String(), nil
+	}
+	return providerMax.String(), nil
+}
+
+// changedFieldsGetter is implemented by both schema.ResourceData and
+// schema.ResourceDiff.
+type changedFieldsGetter interface {
+	GetOk(key string) (interface{}, bool)
+	HasChange(key string) bool
+}
+
+// checkClusterMicroVersion returns an error if the cluster sends arguments
+// the container-infra API microversion does not support. The microversion is
+// requested only if such arguments are sent.
+func checkClusterMicroVersion(d changedFieldsGetter, version func() (string, error)) error {
+	for _, f := range clusterFieldsMicroVersions {
+		if _, ok := d.GetOk(f.field); !ok {
+			continue
+		}
+		if !d.HasChange(f.field) && (f.sentOn == "" || !d.HasChange(f.sentOn)) {
+			continue
+		}
+		v, err := version()
+		if err != nil {
+			return err
+		}
+		current, err := parseMicroVersion(v)
+		if err != nil {
+			return err
+		}
+		if current.less(mustParseMicroVersion(f.version)) {
+			return fmt.Errorf("%s requires container-infra API microversion %s or later, but %s is used",
+				f.field, f.version, current)
+		}
+	}
+	return nil
+}
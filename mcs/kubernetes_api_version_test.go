@@ -0,0 +1,77 @@
+package mcs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateMicroVersion(t *testing.T) {
+	v1 := mustParseMicroVersion("1.1")
+	v20 := mustParseMicroVersion("1.20")
+	v30 := mustParseMicroVersion("1.30")
+	discoverErr := errors.New("not found")
+
+	cases := []struct {
+		name       string
+		pinned     string
+		min, max   microVersion
+		err        error
+		expected   string
+		shouldFail bool
+	}{
+		{name: "server is older", min: v1, max: v20, expected: "1.20"},
+		{name: "server is newer", min: v1, max: v30, expected: magnumAPIMicroVersion},
+		{name: "discovery failed", err: discoverErr, expected: magnumAPIMicroVersion},
+		{name: "pinned", pinned: "1.10", min: v1, max: v30, expected: "1.10"},
+		{name: "pinned without discovery", pinned: "1.10", err: discoverErr, expected: "1.10"},
+		{name: "pinned above server", pinned: "1.22", min: v1, max: v20, shouldFail: true},
+		{name: "pinned above provider", pinned: "1.30", min: v1, max: v30, shouldFail: true},
+		{name: "pinned malformed", pinned: "latest", min: v1, max: v30, shouldFail: true},
+		{name: "server out of range", min: mustParseMicroVersion("2.0"), max: mustParseMicroVersion("2.5"), shouldFail: true},
+	}
+
+	for _, c := range cases {
+		version, err := negotiateMicroVersion(c.pinned, c.min, c.max, c.err)
+		if c.shouldFail {
+			assert.Error(t, err, c.name)
+			continue
+		}
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, c.expected, version, c.name)
+	}
+}
+
+func TestCheckClusterMicroVersion(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceKubernetesCluster().Schema, map[string]interface{}{
+		"insecure_registries": []interface{}{"registry.local"},
+	})
+	version := func(v string) func() (string, error) {
+		return func() (string, error) { return v, nil }
+	}
+
+	assert.NoError(t, checkClusterMicroVersion(d, version("1.23")))
+	err := checkClusterMicroVersion(d, version("1.22"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "insecure_registries requires container-infra API microversion 1.23")
+	}
+
+	policy := schema.TestResourceDataRaw(t, resourceKubernetesCluster().Schema, map[string]interface{}{
+		"upgrade_policy": []interface{}{
+			map[string]interface{}{"rolling_enabled": true, "drain_timeout": "10m"},
+		},
+	})
+	assert.NoError(t, checkClusterMicroVersion(policy, version("1.24")))
+	err = checkClusterMicroVersion(policy, version("1.23"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "upgrade_policy.0.drain_timeout requires container-infra API microversion 1.24")
+	}
+
+	// The microversion is not requested if no gated arguments are set.
+	empty := schema.TestResourceDataRaw(t, resourceKubernetesCluster().Schema, map[string]interface{}{})
+	assert.NoError(t, checkClusterMicroVersion(empty, func() (string, error) {
+		return "", errors.New("unexpected microversion request")
+	}))
+}
@@ -24,22 +24,23 @@ type ContainerClient interface {
 	ServiceURL(parts ...string) string
 }
 
-const magnumAPIMicroVersion = "1.24"
-
-var magnumAPIMicroVersionHeader = map[string]string{
-	"MCS-API-Version": fmt.Sprintf("container-infra %s", magnumAPIMicroVersion),
-}
-
-func addMicroVersionHeader(reqOpts *gophercloud.RequestOpts) {
-	reqOpts.MoreHeaders = magnumAPIMicroVersionHeader
+// addMicroVersionHeader makes the container infra client send API microversion
+// with every request, whether its endpoint comes from the catalog or is overridden.
+func addMicroVersionHeader(client *gophercloud.ServiceClient, version string) {
+	if client.MoreHeaders == nil {
+		client.MoreHeaders = make(map[string]string)
+	}
+	client.MoreHeaders["MCS-API-Version"] = fmt.Sprintf("container-infra %s", version)
 }
 
 type node struct {
-	Name        string     `json:"name"`
-	UUID        string     `json:"uuid"`
-	NodeGroupID string     `json:"node_group_id"`
-	CreatedAt   *time.Time `json:"created_at"`
-	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	Name         string     `json:"name"`
+	UUID         string     `json:"uuid"`
+	NodeGroupID  string     `json:"node_group_id"`
+	CreatedAt    *time.Time `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+	State        string     `json:"state,omitempty"`
+	StatusReason string     `json:"status_reason,omitempty"`
 }
 
 type nodesFlatSchema []map[string]interface{}
@@ -72,22 +73,25 @@ type nodeGroupBatchAddParams struct {
 }
 
 type nodeGroup struct {
-	Name              string    `json:"name,omitempty"`
-	NodeCount         int       `json:"node_count,omitempty"`
-	MaxNodes          int       `json:"max_nodes,omitempty"`
-	MinNodes          int       `json:"min_nodes,omitempty"`
-	VolumeSize        int       `json:"volume_size,omitempty"`
-	VolumeType        string    `json:"volume_type,omitempty"`
-	FlavorID          string    `json:"flavor_id,omitempty"`
-	ImageID           string    `json:"image_id,omitempty"`
-	Autoscaling       bool      `json:"autoscaling_enabled,omitempty"`
-	ClusterID         string    `json:"cluster_id,omitempty"`
-	UUID              string    `json:"uuid,omitempty"`
-	CreatedAt         time.Time `json:"created_at,omitempty"`
-	UpdatedAt         time.Time `json:"updated_at,omitempty"`
-	Nodes             []*node   `json:"nodes,omitempty"`
-	State             string    `json:"state,omitempty"`
-	AvailabilityZones []string  `json:"availability_zones"`
+	Name              string           `json:"name,omitempty"`
+	NodeCount         int              `json:"node_count,omitempty"`
+	MaxNodes          int              `json:"max_nodes,omitempty"`
+	MinNodes          int              `json:"min_nodes,omitempty"`
+	VolumeSize        int              `json:"volume_size,omitempty"`
+	VolumeType        string           `json:"volume_type,omitempty"`
+	FlavorID          string           `json:"flavor_id,omitempty"`
+	ImageID           string           `json:"image_id,omitempty"`
+	Autoscaling       bool             `json:"autoscaling_enabled,omitempty"`
+	ClusterID         string           `json:"cluster_id,omitempty"`
+	UUID              string           `json:"uuid,omitempty"`
+	CreatedAt         *time.Time       `json:"created_at,omitempty"`
+	UpdatedAt         *time.Time       `json:"updated_at,omitempty"`
+	Nodes             []*node          `json:"nodes,omitempty"`
+	State             string           `json:"state,omitempty"`
+	StatusReason      string           `json:"status_reason,omitempty"`
+	AvailabilityZones []string         `json:"availability_zones,omitempty"`
+	Labels            []nodeGroupLabel `json:"labels,omitempty"`
+	Taints            []nodeGroupTaint `json:"taints,omitempty"`
 }
 
 type nodeGroupLabel struct {
@@ -119,8 +123,9 @@ type nodeGroupCreateOpts struct {
 
 // nodeGroupScaleOpts contains options to scale node group
 type nodeGroupScaleOpts struct {
-	Delta    int    `json:"delta" required:"true"`
-	Rollback string `json:"rollback,omitempty"`
+	Delta         int      `json:"delta" required:"true"`
+	Rollback      string   `json:"rollback,omitempty"`
+	NodesToRemove []string `json:"nodes_to_remove,omitempty"`
 }
 
 // clusterCreateOpts contains options to create cluster
@@ -143,6 +148,32 @@ type clusterCreateOpts struct {
 	InsecureRegistries   []string          `json:"insecure_registries,omitempty"`
 }
 
+// clusterTemplateCreateOpts contains options to create cluster template.
+type clusterTemplateCreateOpts struct {
+	Name                string            `json:"name" required:"true"`
+	COE                 string            `json:"coe" required:"true"`
+	ImageID             string            `json:"image_id" required:"true"`
+	APIServerPort       int               `json:"apiserver_port,omitempty"`
+	DNSNameServer       string            `json:"dns_nameserver,omitempty"`
+	DockerStorageDriver string            `json:"docker_storage_driver,omitempty"`
+	DockerVolumeSize    int               `json:"docker_volume_size,omitempty"`
+	ExternalNetworkID   string            `json:"external_network_id,omitempty"`
+	FlavorID            string            `json:"flavor_id,omitempty"`
+	MasterFlavorID      string            `json:"master_flavor_id,omitempty"`
+	FloatingIPEnabled   *bool             `json:"floating_ip_enabled,omitempty"`
+	InsecureRegistry    string            `json:"insecure_registry,omitempty"`
+	KeyPairID           string            `json:"keypair_id,omitempty"`
+	Labels              map[string]string `json:"labels,omitempty"`
+	MasterLBEnabled     *bool             `json:"master_lb_enabled,omitempty"`
+	NetworkDriver       string            `json:"network_driver,omitempty"`
+	NoProxy             string            `json:"no_proxy,omitempty"`
+	Public              *bool             `json:"public,omitempty"`
+	RegistryEnabled     *bool             `json:"registry_enabled,omitempty"`
+	ServerType          string            `json:"server_type,omitempty"`
+	TLSDisabled         *bool             `json:"tls_disabled,omitempty"`
+	VolumeDriver        string            `json:"volume_driver,omitempty"`
+}
+
 type clusterActionsBaseOpts struct {
 	Action  string      `json:"action" required:"true"`
 	Payload interface{} `json:"payload,omitempty"`
@@ -151,6 +182,9 @@ type clusterActionsBaseOpts struct {
 type clusterUpgradeOpts struct {
 	ClusterTemplateID string `json:"cluster_template_id" required:"true"`
 	RollingEnabled    bool   `json:"rolling_enabled"`
+	MaxSurge          int    `json:"max_surge,omitempty"`
+	MaxUnavailable    int    `json:"max_unavailable,omitempty"`
+	DrainTimeout      int    `json:"drain_timeout,omitempty"`
 }
 
 type cluster struct {
@@ -196,6 +230,17 @@ type clusterTemplates struct {
 	Templates []clusterTemplate `json:"clustertemplates"`
 }
 
+// clustersPage is a page of clusters with a link to the next one, if any.
+type clustersPage struct {
+	Clusters []cluster `json:"clusters"`
+	Next     string    `json:"next"`
+}
+
+// clusterNodeGroups is a list of node groups of a cluster.
+type clusterNodeGroups struct {
+	NodeGroups []nodeGroup `json:"nodegroups"`
+}
+
 type optsBuilder interface {
 	Map() (map[string]interface{}, error)
 }
@@ -210,6 +255,12 @@ func (opts *clusterCreateOpts) Map() (map[string]interface{}, error) {
 	return cluster, err
 }
 
+// Map builds request params.
+func (opts *clusterTemplateCreateOpts) Map() (map[string]interface{}, error) {
+	body, err := gophercloud.BuildRequestBody(*opts, "")
+	return body, err
+}
+
 // Map builds request params.
 func (opts *clusterActionsBaseOpts) Map() (map[string]interface{}, error) {
 	cluster, err := gophercloud.BuildRequestBody(*opts, "")
@@ -322,10 +373,39 @@ func (r clusterTemplateResult) Extract() (*clusterTemplate, error) {
 	return s, err
 }
 
+type clusterTemplateDeleteResult struct {
+	gophercloud.ErrResult
+}
+
 type clusterTemplatesResult struct {
 	commonResult
 }
 
+type clusterNodeGroupsResult struct {
+	commonResult
+}
+
+type clustersPageResult struct {
+	commonResult
+}
+
+// Extract parses result into a page of clusters.
+func (r clustersPageResult) Extract() (*clustersPage, error) {
+	var s *clustersPage
+	err := r.ExtractInto(&s)
+	return s, err
+}
+
+// Extract parses result into node groups of the cluster.
+func (r clusterNodeGroupsResult) Extract() ([]nodeGroup, error) {
+	var s *clusterNodeGroups
+	err := r.ExtractInto(&s)
+	if err != nil {
+		return nil, err
+	}
+	return s.NodeGroups, nil
+}
+
 // Extract parses result into params for cluster templates.
 func (r clusterTemplatesResult) Extract() ([]clusterTemplate, error) {
 	var s *clusterTemplates
@@ -360,6 +440,46 @@ func clusterTemplateList(client ContainerClient) (r clusterTemplatesResult) {
 	return
 }
 
+func clusterTemplateCreate(client ContainerClient, opts optsBuilder) (r clusterTemplateResult) {
+	b, err := opts.Map()
+	if err != nil {
+		r.Err = err
+		return
+	}
+	var result *http.Response
+	reqOpts := getRequestOpts(201)
+	result, r.Err = client.Post(baseURL(client, clusterTemplateAPIPath), b, &r.Body, reqOpts)
+	if r.Err == nil {
+		r.Header = result.Header
+	}
+	return
+}
+
+func clusterTemplatePatch(client ContainerClient, id string, opts patchOptsBuilder) (r clusterTemplateResult) {
+	b, err := opts.PatchMap()
+	if err != nil {
+		r.Err = err
+		return
+	}
+	var result *http.Response
+	reqOpts := getRequestOpts(200)
+	result, r.Err = client.Patch(getURL(client, clusterTemplateAPIPath, id), b, &r.Body, reqOpts)
+	if r.Err == nil {
+		r.Header = result.Header
+	}
+	return
+}
+
+func clusterTemplateDelete(client ContainerClient, id string) (r clusterTemplateDeleteResult) {
+	var result *http.Response
+	reqOpts := getRequestOpts(204)
+	result, r.Err = client.Delete(getURL(client, clusterTemplateAPIPath, id), reqOpts)
+	if r.Err == nil {
+		r.Header = result.Header
+	}
+	return
+}
+
 func clusterCreate(client ContainerClient, opts optsBuilder) (r clusters.CreateResult) {
 	b, err := opts.Map()
 	if err != nil {
@@ -390,6 +510,21 @@ func clusterUpgrade(client ContainerClient, id string, opts optsBuilder) (r clus
 	return
 }
 
+func clusterPatch(client ContainerClient, id string, opts patchOptsBuilder) (r clusters.UpdateResult) {
+	b, err := opts.PatchMap()
+	if err != nil {
+		r.Err = err
+		return
+	}
+	reqOpts := getRequestOpts(200, 202)
+	var result *http.Response
+	result, r.Err = client.Patch(getURL(client, clustersAPIPath, id), b, &r.Body, reqOpts)
+	if r.Err == nil {
+		r.Header = result.Header
+	}
+	return
+}
+
 func clusterUpdateMasters(client ContainerClient, id string, opts optsBuilder) (r clusters.UpdateResult) {
 	log.Printf("UPDATE masters for cluster %s", id)
 	b, err := opts.Map()
@@ -406,6 +541,22 @@ func clusterUpdateMasters(client ContainerClient, id string, opts optsBuilder) (
 	return
 }
 
+func clusterBatchAddNodeGroups(client ContainerClient, id string, opts optsBuilder) (r clusters.UpdateResult) {
+	log.Printf("ADD node groups to cluster %s", id)
+	b, err := opts.Map()
+	if err != nil {
+		r.Err = err
+		return
+	}
+	reqOpts := getRequestOpts(200, 202)
+	var result *http.Response
+	result, r.Err = client.Post(actionsURL(client, clustersAPIPath, id), b, &r.Body, reqOpts)
+	if r.Err == nil {
+		r.Header = result.Header
+	}
+	return
+}
+
 func clusterSwitchState(client ContainerClient, id string, opts optsBuilder) (r clusters.UpdateResult) {
 	reqBody, err := opts.Map()
 	if err != nil {
@@ -433,6 +584,37 @@ func clusterGet(client ContainerClient, id string) (r clusterConfigResult) {
 	return
 }
 
+// clusterListPageSize is the number of clusters requested per page.
+const clusterListPageSize = 100
+
+func clusterListPage(client ContainerClient, url string) (r clustersPageResult) {
+	var result *http.Response
+	reqOpts := getRequestOpts(200)
+	result, r.Err = client.Get(url, &r.Body, reqOpts)
+	if r.Err == nil {
+		r.Header = result.Header
+	}
+	return
+}
+
+// clusterList lists all clusters of the project following the links to next pages.
+func clusterList(client ContainerClient) ([]cluster, error) {
+	var all []cluster
+	url := fmt.Sprintf("%s?limit=%d", baseURL(client, clustersAPIPath), clusterListPageSize)
+	for url != "" {
+		page, err := clusterListPage(client, url).Extract()
+		if err != nil {
+			return nil, err
+		}
+		if len(page.Clusters) == 0 {
+			break
+		}
+		all = append(all, page.Clusters...)
+		url = page.Next
+	}
+	return all, nil
+}
+
 func clusterDelete(client ContainerClient, id string) (r clusterDeleteResult) {
 	var result *http.Response
 	reqOpts := getRequestOpts()
@@ -441,6 +623,17 @@ func clusterDelete(client ContainerClient, id string) (r clusterDeleteResult) {
 	return
 }
 
+// clusterNodeGroupList lists node groups of the cluster.
+func clusterNodeGroupList(client ContainerClient, clusterID string) (r clusterNodeGroupsResult) {
+	var result *http.Response
+	reqOpts := getRequestOpts(200)
+	result, r.Err = client.Get(nodeGroupsURL(client, clustersAPIPath, clusterID), &r.Body, reqOpts)
+	if r.Err == nil {
+		r.Header = result.Header
+	}
+	return
+}
+
 func nodeGroupGet(client ContainerClient, id string) (r nodeGroupResult) {
 	var result *http.Response
 	reqOpts := getRequestOpts(200)
@@ -499,6 +692,7 @@ func k8sConfigGet(client ContainerClient, id string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	defer result.Body.Close()
 	buf := bytes.NewBuffer(make([]byte, 0, result.ContentLength))
 	_, err = io.Copy(buf, result.Body)
 	if err != nil {
@@ -66,6 +66,26 @@ func TestPatchOpts(t *testing.T) {
 	assert.Len(t, b, 3)
 }
 
+func TestNodeGroupScaleOpts(t *testing.T) {
+	scaleOpts := nodeGroupScaleOpts{
+		Delta:         -2,
+		NodesToRemove: []string{"node-1", "node-2"},
+	}
+
+	b, _ := scaleOpts.Map()
+
+	assert.Equal(t, map[string]interface{}{
+		"delta":           float64(-2),
+		"nodes_to_remove": []interface{}{"node-1", "node-2"},
+	}, b)
+
+	scaleOpts = nodeGroupScaleOpts{Delta: 1}
+
+	b, _ = scaleOpts.Map()
+
+	assert.Equal(t, map[string]interface{}{"delta": float64(1)}, b)
+}
+
 func TestAddBatchOpts(t *testing.T) {
 
 	addGroups := []nodeGroup{
@@ -89,6 +109,10 @@ func TestAddBatchOpts(t *testing.T) {
 	assert.IsType(t, []interface{}{}, b["payload"])
 	assert.Len(t, b["payload"], 2)
 	assert.Len(t, b, 2)
+	assert.Equal(t, map[string]interface{}{
+		"name":      "test1",
+		"flavor_id": "95663bae-6763-4a53-9424-831975285cc1",
+	}, b["payload"].([]interface{})[0])
 }
 
 func k8sconfigFixture(t *testing.T, id string) {
@@ -134,3 +158,34 @@ func TestK8sConfigGetError(t *testing.T) {
 	_, err := k8sConfigGet(serviceClient, "notfound")
 	assert.Error(t, err)
 }
+
+func TestClusterList(t *testing.T) {
+	th.SetupHTTP()
+	defer th.TeardownHTTP()
+
+	th.Mux.HandleFunc("/clusters", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(t, r, "GET")
+		th.TestHeader(t, r, "X-Auth-Token", fake.TokenID)
+		assert.Equal(t, fmt.Sprint(clusterListPageSize), r.URL.Query().Get("limit"))
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Query().Get("marker") {
+		case "":
+			fmt.Fprintf(w, `{"clusters": [{"uuid": "1"}, {"uuid": "2"}], "next": "%sclusters?limit=%d&marker=2"}`,
+				th.Endpoint(), clusterListPageSize)
+		case "2":
+			fmt.Fprint(w, `{"clusters": [{"uuid": "3"}]}`)
+		default:
+			t.Errorf("unexpected marker %s", r.URL.Query().Get("marker"))
+		}
+	})
+
+	clusters, err := clusterList(fake.ServiceClient())
+	assert.NoError(t, err)
+	ids := make([]string, len(clusters))
+	for i, c := range clusters {
+		ids[i] = c.UUID
+	}
+	assert.Equal(t, []string{"1", "2", "3"}, ids)
+}
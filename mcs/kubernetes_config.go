@@ -0,0 +1,97 @@
+package mcs
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// kubeConfig is the part of a kubeconfig file used to access a cluster.
+type kubeConfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKeyData         string `yaml:"client-key-data"`
+			Token                 string `yaml:"token"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// kubeCredentials are credentials to access a cluster, in the form accepted
+// by the kubernetes and helm providers.
+type kubeCredentials struct {
+	Host                 string
+	ClusterCACertificate string
+	ClientCertificate    string
+	ClientKey            string
+	Token                string
+}
+
+// parseKubeConfig extracts credentials of the current context from kubeconfig.
+// The first cluster and user are used if the current context is not set.
+func parseKubeConfig(raw string) (*kubeCredentials, error) {
+	var c kubeConfig
+	if err := yaml.Unmarshal([]byte(raw), &c); err != nil {
+		return nil, fmt.Errorf("error parsing kubeconfig: %s", err)
+	}
+	if len(c.Clusters) == 0 || len(c.Users) == 0 {
+		return nil, fmt.Errorf("kubeconfig has no clusters or users")
+	}
+
+	clusterIdx, userIdx := 0, 0
+	for _, ctx := range c.Contexts {
+		if ctx.Name != c.CurrentContext {
+			continue
+		}
+		for i := range c.Clusters {
+			if c.Clusters[i].Name == ctx.Context.Cluster {
+				clusterIdx = i
+			}
+		}
+		for i := range c.Users {
+			if c.Users[i].Name == ctx.Context.User {
+				userIdx = i
+			}
+		}
+	}
+	cluster, user := c.Clusters[clusterIdx].Cluster, c.Users[userIdx].User
+
+	creds := &kubeCredentials{
+		Host:  cluster.Server,
+		Token: user.Token,
+	}
+	for _, f := range []struct {
+		name  string
+		data  string
+		field *string
+	}{
+		{"certificate-authority-data", cluster.CertificateAuthorityData, &creds.ClusterCACertificate},
+		{"client-certificate-data", user.ClientCertificateData, &creds.ClientCertificate},
+		{"client-key-data", user.ClientKeyData, &creds.ClientKey},
+	} {
+		decoded, err := base64.StdEncoding.DecodeString(f.data)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding kubeconfig %s: %s", f.name, err)
+		}
+		*f.field = string(decoded)
+	}
+
+	return creds, nil
+}
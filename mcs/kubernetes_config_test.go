@@ -0,0 +1,60 @@
+package mcs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testKubeConfig = `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: Y2Etb3RoZXI=
+    server: https://10.0.0.2:6443
+  name: other
+- cluster:
+    certificate-authority-data: Y2E=
+    server: https://10.0.0.1:6443
+  name: test
+contexts:
+- context:
+    cluster: test
+    user: admin_test
+  name: default/test
+current-context: default/test
+kind: Config
+users:
+- name: other
+  user:
+    token: other-token
+- name: admin_test
+  user:
+    client-certificate-data: Y2VydA==
+    client-key-data: a2V5
+    token: token
+`
+
+func TestParseKubeConfig(t *testing.T) {
+	creds, err := parseKubeConfig(testKubeConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, &kubeCredentials{
+		Host:                 "https://10.0.0.1:6443",
+		ClusterCACertificate: "ca",
+		ClientCertificate:    "cert",
+		ClientKey:            "key",
+		Token:                "token",
+	}, creds)
+}
+
+func TestParseKubeConfigInvalid(t *testing.T) {
+	_, err := parseKubeConfig("kind: Config\n")
+	assert.Error(t, err)
+
+	_, err = parseKubeConfig(`clusters:
+- cluster:
+    certificate-authority-data: "not base64"
+users:
+- name: admin
+`)
+	assert.Error(t, err)
+}
@@ -2,9 +2,16 @@ package mcs
 
 import (
 	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/gophercloud/gophercloud"
+	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/mitchellh/mapstructure"
 )
 
@@ -39,6 +46,118 @@ func extractKubernetesLabelsMap(v map[string]interface{}) (map[string]string, er
 	return m, nil
 }
 
+// immutableClusterLabels are the cluster labels the container-infra API
+// refuses to change after the cluster is created. The API doesn't expose the
+// list: these labels configure the cluster infrastructure (the network, the
+// subnet, the calico pool and the image registry prefix) which is only built
+// on create, and kube_tag is changed by upgrading the cluster template. Keep
+// in sync with the labels rejected by PATCH /clusters/{id} and with the
+// emulator in internal/emulator.
+var immutableClusterLabels = map[string]bool{
+	"calico_ipv4pool":        true,
+	"container_infra_prefix": true,
+	"fixed_network":          true,
+	"fixed_subnet":           true,
+	"kube_tag":               true,
+}
+
+// systemLabelDomains are domains of labels and taints which are set by the
+// service itself and so are not managed by the provider.
+var systemLabelDomains = []string{"kubernetes.io", "k8s.io", "mcs.mail.ru"}
+
+// isSystemLabel checks whether the label key is prefixed with a system domain
+// or its subdomain, e.g. node.kubernetes.io/instance-type.
+func isSystemLabel(key string) bool {
+	for _, domain := range systemLabelDomains {
+		if strings.HasPrefix(key, domain+"/") || strings.Contains(key, "."+domain+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// filterClusterLabels drops the cluster labels which are not managed by the
+// provider. The labels set by the service have system prefixes and are always
+// dropped. The labels inherited from the cluster template have the same values
+// as in the template and are dropped unless they are already known, e.g. set
+// in the configuration. If the template labels are nil, i.e. the template is
+// deleted, all the labels which are not known are considered inherited.
+func filterClusterLabels(v, templateLabels map[string]string, known map[string]interface{}) map[string]string {
+	labels := make(map[string]string, len(v))
+	for key, value := range v {
+		if isSystemLabel(key) {
+			continue
+		}
+		if _, ok := known[key]; !ok {
+			if templateValue, inherited := templateLabels[key]; templateLabels == nil || inherited && templateValue == value {
+				continue
+			}
+		}
+		labels[key] = value
+	}
+	return labels
+}
+
+func filterSystemNodeGroupLabels(v []nodeGroupLabel) []nodeGroupLabel {
+	labels := make([]nodeGroupLabel, 0, len(v))
+	for _, label := range v {
+		if !isSystemLabel(label.Key) {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+func filterSystemNodeGroupTaints(v []nodeGroupTaint) []nodeGroupTaint {
+	taints := make([]nodeGroupTaint, 0, len(v))
+	for _, taint := range v {
+		if !isSystemLabel(taint.Key) {
+			taints = append(taints, taint)
+		}
+	}
+	return taints
+}
+
+// changedImmutableLabels returns sorted names of immutable labels which are
+// removed or changed between the old and the new labels. An immutable label
+// which is not in the old labels may be inherited from the cluster template
+// with the same value, so adding it is left to the API to validate.
+func changedImmutableLabels(oldLabels, newLabels map[string]interface{}) []string {
+	var changed []string
+	for name := range immutableClusterLabels {
+		oldValue, oldOk := oldLabels[name]
+		newValue, newOk := newLabels[name]
+		if oldOk && (!newOk || oldValue != newValue) {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// mergeClusterLabels applies the changes between the old and the new labels
+// managed by the provider to the current labels of the cluster, so the labels
+// set by the service or the cluster template are kept.
+func mergeClusterLabels(current map[string]string, oldLabels, newLabels map[string]interface{}) (map[string]string, error) {
+	labels, err := extractKubernetesLabelsMap(newLabels)
+	if err != nil {
+		return nil, err
+	}
+	merged := make(map[string]string, len(current)+len(labels))
+	for key, value := range current {
+		merged[key] = value
+	}
+	for key := range oldLabels {
+		if _, ok := newLabels[key]; !ok {
+			delete(merged, key)
+		}
+	}
+	for key, value := range labels {
+		merged[key] = value
+	}
+	return merged, nil
+}
+
 func extractNodeGroupLabelsList(v []interface{}) ([]nodeGroupLabel, error) {
 	labels := make([]nodeGroupLabel, len(v))
 	for i, label := range v {
@@ -99,3 +218,222 @@ func kubernetesStateRefreshFunc(client ContainerClient, clusterID string) resour
 		return c, string(c.NewStatus), nil
 	}
 }
+
+// isNodeGroupFailed checks whether the node group or node state is a failure,
+// e.g. ERROR or CREATE_FAILED.
+func isNodeGroupFailed(state string) bool {
+	return state == string(clusterStatusError) || strings.HasSuffix(state, "_FAILED")
+}
+
+// nodeGroupError describes the failed node group along with its failed nodes.
+func nodeGroupError(ng *nodeGroup) error {
+	msg := fmt.Sprintf("mcs_kubernetes_node_group %s is in %s state", ng.UUID, ng.State)
+	if ng.StatusReason != "" {
+		msg += ": " + ng.StatusReason
+	}
+	var failed []string
+	for _, n := range ng.Nodes {
+		if n == nil || !isNodeGroupFailed(n.State) {
+			continue
+		}
+		failed = append(failed, fmt.Sprintf("node %s (%s) is in %s state: %s", n.Name, n.UUID, n.State, n.StatusReason))
+	}
+	if len(failed) > 0 {
+		msg += "; " + strings.Join(failed, "; ")
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// nodeGroupStateRefreshFunc reports the cluster status like
+// kubernetesStateRefreshFunc, but fails as soon as the node group fails.
+// The node group is skipped once it is not found, e.g. while being deleted.
+func nodeGroupStateRefreshFunc(client ContainerClient, clusterID, nodeGroupID string) resource.StateRefreshFunc {
+	clusterRefresh := kubernetesStateRefreshFunc(client, clusterID)
+	return func() (interface{}, string, error) {
+		ng, err := nodeGroupGet(client, nodeGroupID).Extract()
+		if err != nil {
+			if _, ok := err.(gophercloud.ErrDefault404); !ok {
+				return nil, "", err
+			}
+		} else if isNodeGroupFailed(ng.State) {
+			return ng, ng.State, nodeGroupError(ng)
+		}
+		return clusterRefresh()
+	}
+}
+
+// isClusterTemplateDeprecated checks whether the template is already deprecated.
+func isClusterTemplateDeprecated(t *clusterTemplate, now time.Time) bool {
+	return !t.DeprecatedAt.IsZero() && !t.DeprecatedAt.After(now)
+}
+
+// validateNodeGroupSize checks that the node count of the node group is within
+// its bounds and that the bounds are set if autoscaling is enabled. The node
+// count is managed by the autoscaler when autoscaling is enabled, so it is not
+// checked then. Zero values are considered not set.
+func validateNodeGroupSize(nodeCount, minNodes, maxNodes int, autoscaling bool) error {
+	if autoscaling && (minNodes == 0 || maxNodes == 0) {
+		return fmt.Errorf("min_nodes and max_nodes must be set when autoscaling_enabled is true")
+	}
+	if minNodes > 0 && maxNodes > 0 && minNodes > maxNodes {
+		return fmt.Errorf("min_nodes %d must not be greater than max_nodes %d", minNodes, maxNodes)
+	}
+	if autoscaling || nodeCount == 0 {
+		return nil
+	}
+	if minNodes > 0 && nodeCount < minNodes {
+		return fmt.Errorf("node_count %d must not be less than min_nodes %d", nodeCount, minNodes)
+	}
+	if maxNodes > 0 && nodeCount > maxNodes {
+		return fmt.Errorf("node_count %d must not be greater than max_nodes %d", nodeCount, maxNodes)
+	}
+	return nil
+}
+
+// validateClusterUpgrade checks that a cluster of the template can be upgraded
+// to the target one. Kubernetes can't be downgraded and minor versions can't
+// be skipped. Versions are not compared if they are not semantic versions.
+func validateClusterUpgrade(from, to *clusterTemplate, now time.Time) error {
+	if isClusterTemplateDeprecated(to, now) {
+		return fmt.Errorf("cluster template %s is deprecated since %s",
+			to.UUID, to.DeprecatedAt.Format(time.RFC3339))
+	}
+
+	fromVersion, err := version.NewVersion(from.Version)
+	if err != nil {
+		return nil
+	}
+	toVersion, err := version.NewVersion(to.Version)
+	if err != nil {
+		return nil
+	}
+
+	fromSegments, toSegments := fromVersion.Segments(), toVersion.Segments()
+	switch {
+	case toVersion.LessThan(fromVersion):
+		return fmt.Errorf("kubernetes version can't be downgraded from %s to %s", from.Version, to.Version)
+	case toSegments[0] != fromSegments[0] || toSegments[1] > fromSegments[1]+1:
+		return fmt.Errorf("kubernetes version can't be upgraded from %s to %s, minor versions can't be skipped",
+			from.Version, to.Version)
+	}
+	return nil
+}
+
+// clusterNodeGroupUpdate is an inline node group of a cluster updated in place.
+type clusterNodeGroupUpdate struct {
+	old, new map[string]interface{}
+}
+
+// immutableNodeGroupAttributes are the attributes of an inline node group of
+// a cluster which can't be updated in place, so the node group is replaced.
+var immutableNodeGroupAttributes = []string{"flavor_id", "volume_size", "volume_type", "availability_zones"}
+
+// diffClusterNodeGroups matches the old and new inline node groups of a
+// cluster by their names. It returns the names of the node groups to delete,
+// the node groups to add and the node groups to update in place. The node
+// groups with changed immutable attributes are both deleted and added.
+func diffClusterNodeGroups(oldGroups, newGroups []interface{}) ([]string, []interface{}, []clusterNodeGroupUpdate) {
+	oldByName := make(map[string]map[string]interface{}, len(oldGroups))
+	for _, raw := range oldGroups {
+		ng := raw.(map[string]interface{})
+		oldByName[ng["name"].(string)] = ng
+	}
+
+	var removed []string
+	var added []interface{}
+	var updated []clusterNodeGroupUpdate
+	kept := make(map[string]bool, len(newGroups))
+	for _, raw := range newGroups {
+		ng := raw.(map[string]interface{})
+		name := ng["name"].(string)
+		old, ok := oldByName[name]
+		if !ok {
+			added = append(added, ng)
+			continue
+		}
+		kept[name] = true
+		replaced := false
+		for _, key := range immutableNodeGroupAttributes {
+			if !reflect.DeepEqual(old[key], ng[key]) {
+				replaced = true
+				break
+			}
+		}
+		if replaced {
+			removed = append(removed, name)
+			added = append(added, ng)
+			continue
+		}
+		updated = append(updated, clusterNodeGroupUpdate{old: old, new: ng})
+	}
+	for _, raw := range oldGroups {
+		name := raw.(map[string]interface{})["name"].(string)
+		if !kept[name] {
+			removed = append(removed, name)
+		}
+	}
+	return removed, added, updated
+}
+
+// expandNodeGroupNodesToRemove resolves the nodes to remove, given by their
+// names or UUIDs, to the UUIDs of the nodes of the node group. The nodes which
+// are not in the node group are already removed, so they are skipped. The
+// number of the nodes to remove must not exceed the number of removed nodes.
+func expandNodeGroupNodesToRemove(nodesToRemove []interface{}, nodes []*node, removed int) ([]string, error) {
+	uuids := make([]string, 0, len(nodesToRemove))
+	seen := make(map[string]bool, len(nodesToRemove))
+	for _, raw := range nodesToRemove {
+		id := raw.(string)
+		var found *node
+		for _, n := range nodes {
+			if n.UUID == id || n.Name == id {
+				found = n
+				break
+			}
+		}
+		if found == nil {
+			log.Printf("[DEBUG] Node %s to remove is not found in the node group, skipping it", id)
+			continue
+		}
+		if !seen[found.UUID] {
+			seen[found.UUID] = true
+			uuids = append(uuids, found.UUID)
+		}
+	}
+	if len(uuids) > removed {
+		return nil, fmt.Errorf("nodes_to_remove has %d nodes, but node_count decreases by %d", len(uuids), removed)
+	}
+	return uuids, nil
+}
+
+// nodeGroupNodesSchema returns the schema of the nodes of the node group.
+func nodeGroupNodesSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"uuid": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"name": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"node_group_id": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"created_at": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"updated_at": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+			},
+		},
+	}
+}
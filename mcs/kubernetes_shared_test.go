@@ -1,9 +1,14 @@
 package mcs
 
 import (
+	"fmt"
+	"net/http"
 	"sort"
 	"testing"
+	"time"
 
+	th "github.com/gophercloud/gophercloud/testhelper"
+	fake "github.com/gophercloud/gophercloud/testhelper/client"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -23,6 +28,103 @@ func TestExpandContainerInfraLabelsMap(t *testing.T) {
 	assert.Equal(t, expectedLabels, actualLabels)
 }
 
+func TestChangedImmutableLabels(t *testing.T) {
+	oldLabels := map[string]interface{}{
+		"kube_tag":           "v1.20.4",
+		"ingress_controller": "nginx",
+		"fixed_subnet":       "subnet",
+	}
+	newLabels := map[string]interface{}{
+		"kube_tag":              "v1.21.4",
+		"prometheus_monitoring": "true",
+		"calico_ipv4pool":       "10.100.0.0/16",
+	}
+	assert.Equal(t, []string{"fixed_subnet", "kube_tag"}, changedImmutableLabels(oldLabels, newLabels))
+
+	assert.Empty(t, changedImmutableLabels(oldLabels, map[string]interface{}{
+		"kube_tag":     "v1.20.4",
+		"fixed_subnet": "subnet",
+	}))
+}
+
+func TestMergeClusterLabels(t *testing.T) {
+	current := map[string]string{
+		"kube_tag":                     "v1.20.4",
+		"mcs.mail.ru/cluster-template": "1",
+		"ingress_controller":           "nginx",
+		"docker_registry_enabled":      "true",
+	}
+	oldLabels := map[string]interface{}{
+		"ingress_controller":      "nginx",
+		"docker_registry_enabled": "true",
+	}
+	newLabels := map[string]interface{}{
+		"docker_registry_enabled": "false",
+		"prometheus_monitoring":   "true",
+	}
+
+	merged, err := mergeClusterLabels(current, oldLabels, newLabels)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"kube_tag":                     "v1.20.4",
+		"mcs.mail.ru/cluster-template": "1",
+		"docker_registry_enabled":      "false",
+		"prometheus_monitoring":        "true",
+	}, merged)
+}
+
+func TestFilterClusterLabels(t *testing.T) {
+	labels := map[string]string{
+		"ingress_controller":           "nginx",
+		"kube_tag":                     "v1.20.4",
+		"fixed_network":                "network",
+		"docker_volume_size":           "20",
+		"mcs.mail.ru/cluster-template": "1",
+	}
+	templateLabels := map[string]string{
+		"kube_tag":           "v1.20.4",
+		"docker_volume_size": "10",
+	}
+
+	assert.Equal(t, map[string]string{
+		"ingress_controller": "nginx",
+		"fixed_network":      "network",
+		"docker_volume_size": "20",
+	}, filterClusterLabels(labels, templateLabels, map[string]interface{}{}))
+
+	// The known labels are kept even if they are inherited.
+	assert.Equal(t, map[string]string{
+		"ingress_controller": "nginx",
+		"kube_tag":           "v1.20.4",
+		"fixed_network":      "network",
+		"docker_volume_size": "20",
+	}, filterClusterLabels(labels, templateLabels, map[string]interface{}{"kube_tag": "v1.20.4"}))
+
+	// Without the template only the known labels are kept.
+	assert.Equal(t, map[string]string{
+		"ingress_controller": "nginx",
+	}, filterClusterLabels(labels, nil, map[string]interface{}{"ingress_controller": "nginx"}))
+}
+
+func TestFilterSystemLabels(t *testing.T) {
+	assert.True(t, isSystemLabel("mcs.mail.ru/cluster-template"))
+	assert.True(t, isSystemLabel("node.kubernetes.io/instance-type"))
+	assert.False(t, isSystemLabel("example.com/kubernetes.io"))
+	assert.False(t, isSystemLabel("ingress_controller"))
+
+	nodeGroupLabels := []nodeGroupLabel{
+		{Key: "kubernetes.io/role", Value: "node"},
+		{Key: "env", Value: "test"},
+	}
+	assert.Equal(t, []nodeGroupLabel{{Key: "env", Value: "test"}}, filterSystemNodeGroupLabels(nodeGroupLabels))
+
+	taints := []nodeGroupTaint{
+		{Key: "node.kubernetes.io/unschedulable", Effect: "NoSchedule"},
+		{Key: "dedicated", Value: "db", Effect: "NoSchedule"},
+	}
+	assert.Equal(t, []nodeGroupTaint{{Key: "dedicated", Value: "db", Effect: "NoSchedule"}}, filterSystemNodeGroupTaints(taints))
+}
+
 func TestExpandKubernetesGroupMap(t *testing.T) {
 	ncount, maxn, minn, vs := 2, 3, 1, 10
 
@@ -128,3 +230,252 @@ func TestExtractNodeGroupTaintsList(t *testing.T) {
 	assert.Equal(t, err, nil)
 	assert.Equal(t, expectedTaints, actualTaints)
 }
+
+func TestIsNodeGroupFailed(t *testing.T) {
+	assert.True(t, isNodeGroupFailed("ERROR"))
+	assert.True(t, isNodeGroupFailed("CREATE_FAILED"))
+	assert.False(t, isNodeGroupFailed("RUNNING"))
+	assert.False(t, isNodeGroupFailed("RECONCILING"))
+	assert.False(t, isNodeGroupFailed(""))
+}
+
+func nodeGroupStateFixture(t *testing.T, nodeGroupBody string) {
+	th.Mux.HandleFunc("/nodegroups/ng", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(t, r, "GET")
+		if nodeGroupBody == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, nodeGroupBody)
+	})
+	th.Mux.HandleFunc("/clusters/cluster", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(t, r, "GET")
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"uuid": "cluster", "new_status": "RECONCILING"}`)
+	})
+}
+
+func TestNodeGroupStateRefreshFunc(t *testing.T) {
+	th.SetupHTTP()
+	defer th.TeardownHTTP()
+
+	nodeGroupStateFixture(t, `{"uuid": "ng", "state": "RUNNING"}`)
+
+	_, state, err := nodeGroupStateRefreshFunc(fake.ServiceClient(), "cluster", "ng")()
+	assert.NoError(t, err)
+	assert.Equal(t, string(clusterStatusReconciling), state)
+}
+
+func TestNodeGroupStateRefreshFuncNotFound(t *testing.T) {
+	th.SetupHTTP()
+	defer th.TeardownHTTP()
+
+	nodeGroupStateFixture(t, "")
+
+	_, state, err := nodeGroupStateRefreshFunc(fake.ServiceClient(), "cluster", "ng")()
+	assert.NoError(t, err)
+	assert.Equal(t, string(clusterStatusReconciling), state)
+}
+
+func TestNodeGroupStateRefreshFuncFailed(t *testing.T) {
+	th.SetupHTTP()
+	defer th.TeardownHTTP()
+
+	nodeGroupStateFixture(t, `{
+		"uuid": "ng",
+		"state": "ERROR",
+		"status_reason": "scaling failed",
+		"nodes": [
+			{"name": "ng-0", "uuid": "node-0", "state": "RUNNING"},
+			{"name": "ng-1", "uuid": "node-1", "state": "ERROR", "status_reason": "no valid host was found"}
+		]
+	}`)
+
+	_, state, err := nodeGroupStateRefreshFunc(fake.ServiceClient(), "cluster", "ng")()
+	assert.Equal(t, "ERROR", state)
+	assert.EqualError(t, err, "mcs_kubernetes_node_group ng is in ERROR state: scaling failed; "+
+		"node ng-1 (node-1) is in ERROR state: no valid host was found")
+}
+
+func TestValidateClusterUpgrade(t *testing.T) {
+	now := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	template := func(version string, deprecatedAt time.Time) *clusterTemplate {
+		var t clusterTemplate
+		t.UUID, t.Version, t.DeprecatedAt = "template-"+version, version, deprecatedAt
+		return &t
+	}
+
+	tests := map[string]struct {
+		from, to *clusterTemplate
+		err      string
+	}{
+		"next minor version": {
+			from: template("1.20.4", time.Time{}),
+			to:   template("1.21.4", time.Time{}),
+		},
+		"patch version": {
+			from: template("1.20.4", time.Time{}),
+			to:   template("1.20.7", now.AddDate(0, 1, 0)),
+		},
+		"not semantic versions": {
+			from: template("custom", time.Time{}),
+			to:   template("1.21.4", time.Time{}),
+		},
+		"deprecated template": {
+			from: template("1.19.9", time.Time{}),
+			to:   template("1.20.4", now.AddDate(0, -1, 0)),
+			err:  "cluster template template-1.20.4 is deprecated since 2021-09-01T00:00:00Z",
+		},
+		"downgrade": {
+			from: template("1.21.4", time.Time{}),
+			to:   template("1.20.4", time.Time{}),
+			err:  "kubernetes version can't be downgraded from 1.21.4 to 1.20.4",
+		},
+		"skipped minor version": {
+			from: template("1.19.9", time.Time{}),
+			to:   template("1.21.4", time.Time{}),
+			err:  "kubernetes version can't be upgraded from 1.19.9 to 1.21.4, minor versions can't be skipped",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateClusterUpgrade(tt.from, tt.to, now)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateNodeGroupSize(t *testing.T) {
+	tests := map[string]struct {
+		nodeCount, minNodes, maxNodes int
+		autoscaling                   bool
+		err                           string
+	}{
+		"without bounds": {
+			nodeCount: 3,
+		},
+		"within bounds": {
+			nodeCount: 3, minNodes: 1, maxNodes: 5, autoscaling: true,
+		},
+		"unknown node count": {
+			minNodes: 1, maxNodes: 5,
+		},
+		"autoscaling without bounds": {
+			nodeCount: 3, minNodes: 1, autoscaling: true,
+			err: "min_nodes and max_nodes must be set when autoscaling_enabled is true",
+		},
+		"min nodes greater than max nodes": {
+			nodeCount: 3, minNodes: 4, maxNodes: 2,
+			err: "min_nodes 4 must not be greater than max_nodes 2",
+		},
+		"node count less than min nodes": {
+			nodeCount: 1, minNodes: 2,
+			err: "node_count 1 must not be less than min_nodes 2",
+		},
+		"node count greater than max nodes": {
+			nodeCount: 6, minNodes: 1, maxNodes: 5,
+			err: "node_count 6 must not be greater than max_nodes 5",
+		},
+		"autoscaling node count out of bounds": {
+			nodeCount: 6, minNodes: 1, maxNodes: 5, autoscaling: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateNodeGroupSize(tt.nodeCount, tt.minNodes, tt.maxNodes, tt.autoscaling)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDiffClusterNodeGroups(t *testing.T) {
+	nodeGroup := func(name string, nodeCount int, flavorID string) map[string]interface{} {
+		return map[string]interface{}{"name": name, "node_count": nodeCount, "flavor_id": flavorID}
+	}
+	oldGroups := []interface{}{
+		nodeGroup("kept", 1, "flavor"),
+		nodeGroup("scaled", 1, "flavor"),
+		nodeGroup("replaced", 1, "flavor"),
+		nodeGroup("removed", 1, "flavor"),
+	}
+	newGroups := []interface{}{
+		nodeGroup("added", 1, "flavor"),
+		nodeGroup("kept", 1, "flavor"),
+		nodeGroup("scaled", 3, "flavor"),
+		nodeGroup("replaced", 1, "new-flavor"),
+	}
+
+	removed, added, updated := diffClusterNodeGroups(oldGroups, newGroups)
+	assert.Equal(t, []string{"replaced", "removed"}, removed)
+	assert.Equal(t, []interface{}{newGroups[0], newGroups[3]}, added)
+	assert.Equal(t, []clusterNodeGroupUpdate{
+		{old: oldGroups[0].(map[string]interface{}), new: newGroups[1].(map[string]interface{})},
+		{old: oldGroups[1].(map[string]interface{}), new: newGroups[2].(map[string]interface{})},
+	}, updated)
+}
+
+func TestExpandNodeGroupNodesToRemove(t *testing.T) {
+	nodes := []*node{
+		{Name: "ng-0", UUID: "node-0"},
+		{Name: "ng-1", UUID: "node-1"},
+		{Name: "ng-2", UUID: "node-2"},
+	}
+
+	tests := map[string]struct {
+		nodesToRemove []interface{}
+		removed       int
+		expected      []string
+		err           string
+	}{
+		"none": {
+			nodesToRemove: []interface{}{},
+			removed:       2,
+			expected:      []string{},
+		},
+		"by uuid and name": {
+			nodesToRemove: []interface{}{"node-2", "ng-0"},
+			removed:       2,
+			expected:      []string{"node-2", "node-0"},
+		},
+		"same node twice": {
+			nodesToRemove: []interface{}{"node-1", "ng-1"},
+			removed:       1,
+			expected:      []string{"node-1"},
+		},
+		"already removed node": {
+			nodesToRemove: []interface{}{"ng-3", "ng-1"},
+			removed:       1,
+			expected:      []string{"node-1"},
+		},
+		"too many nodes": {
+			nodesToRemove: []interface{}{"ng-0", "ng-1"},
+			removed:       1,
+			err:           "nodes_to_remove has 2 nodes, but node_count decreases by 1",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			uuids, err := expandNodeGroupNodesToRemove(tt.nodesToRemove, nodes, tt.removed)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, uuids)
+		})
+	}
+}
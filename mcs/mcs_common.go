@@ -1,10 +1,13 @@
 package mcs
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/gophercloud/gophercloud"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 )
 
 func getRequestOpts(codes ...int) *gophercloud.RequestOpts {
@@ -14,7 +17,6 @@ func getRequestOpts(codes ...int) *gophercloud.RequestOpts {
 	if len(codes) != 0 {
 		reqOpts.OkCodes = codes
 	}
-	addMicroVersionHeader(reqOpts)
 	return reqOpts
 }
 
@@ -33,3 +35,73 @@ func (t *dateTimeWithoutTZFormat) UnmarshalJSON(b []byte) (err error) {
 	t.Time, err = time.Parse(layout, s)
 	return
 }
+
+// deletionProtectionSchema is the schema of the deletion_protection argument
+// which prevents a resource from being deleted or replaced.
+func deletionProtectionSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeBool,
+		Optional: true,
+		Default:  false,
+	}
+}
+
+// checkDeletionProtection returns an error if the resource can't be deleted
+// because its deletion protection is enabled.
+func checkDeletionProtection(d *schema.ResourceData, resourceType string) error {
+	if d.Get("deletion_protection").(bool) {
+		return fmt.Errorf("%s %s can't be deleted while deletion_protection is enabled", resourceType, d.Id())
+	}
+	return nil
+}
+
+// customizeDiffDeletionProtection fails the plan which replaces an existing
+// resource with deletion protection enabled. Deletion protection must be
+// disabled by a separate apply, so the value in the state is checked.
+func customizeDiffDeletionProtection(d *schema.ResourceDiff, resourceSchema map[string]*schema.Schema, resourceType string) error {
+	if d.Id() == "" {
+		return nil
+	}
+	if protected, _ := d.GetChange("deletion_protection"); !protected.(bool) {
+		return nil
+	}
+
+	replaced := forceNewChanges(d, resourceSchema, "")
+	if len(replaced) == 0 {
+		return nil
+	}
+	sort.Strings(replaced)
+	return fmt.Errorf("%s %s can't be replaced while deletion_protection is enabled, changed attributes: %s",
+		resourceType, d.Id(), strings.Join(replaced, ", "))
+}
+
+// forceNewChanges returns the changed attributes which force a new resource.
+// A list block is replaced if it is ForceNew and the number of its elements is
+// changed, otherwise its elements are checked the same way.
+func forceNewChanges(d *schema.ResourceDiff, resourceSchema map[string]*schema.Schema, prefix string) []string {
+	var changes []string
+	for name, s := range resourceSchema {
+		key := prefix + name
+		if !d.HasChange(key) {
+			continue
+		}
+		if elem, ok := s.Elem.(*schema.Resource); ok && s.Type == schema.TypeList {
+			o, n := d.GetChange(key)
+			oldItems, newItems := o.([]interface{}), n.([]interface{})
+			if len(oldItems) != len(newItems) {
+				if s.ForceNew {
+					changes = append(changes, key)
+				}
+				continue
+			}
+			for i := range newItems {
+				changes = append(changes, forceNewChanges(d, elem.Schema, fmt.Sprintf("%s.%d.", key, i))...)
+			}
+			continue
+		}
+		if s.ForceNew {
+			changes = append(changes, key)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,125 @@
+package mcs
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomizeDiffDeletionProtection(t *testing.T) {
+	resourceSchema := map[string]*schema.Schema{
+		"name": {
+			Type:     schema.TypeString,
+			Required: true,
+			ForceNew: true,
+		},
+		"size": {
+			Type:     schema.TypeInt,
+			Required: true,
+		},
+		"datastore": {
+			Type:     schema.TypeList,
+			Required: true,
+			ForceNew: true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"version": {
+						Type:     schema.TypeString,
+						Required: true,
+						ForceNew: true,
+					},
+					"settings": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+				},
+			},
+		},
+		"deletion_protection": deletionProtectionSchema(),
+	}
+	r := &schema.Resource{
+		Schema: resourceSchema,
+		CustomizeDiff: func(d *schema.ResourceDiff, meta interface{}) error {
+			return customizeDiffDeletionProtection(d, resourceSchema, "mcs_test")
+		},
+	}
+
+	state := func(protected string) *terraform.InstanceState {
+		return &terraform.InstanceState{
+			ID: "id",
+			Attributes: map[string]string{
+				"id":                   "id",
+				"name":                 "test",
+				"size":                 "1",
+				"datastore.#":          "1",
+				"datastore.0.version":  "1.0",
+				"datastore.0.settings": "default",
+				"deletion_protection":  protected,
+			},
+		}
+	}
+	config := func(name string, size int, datastores []interface{}, protected bool) *terraform.ResourceConfig {
+		return terraform.NewResourceConfigRaw(map[string]interface{}{
+			"name":                name,
+			"size":                size,
+			"datastore":           datastores,
+			"deletion_protection": protected,
+		})
+	}
+	datastore := func(version, settings string) map[string]interface{} {
+		return map[string]interface{}{"version": version, "settings": settings}
+	}
+
+	tests := map[string]struct {
+		state  *terraform.InstanceState
+		config *terraform.ResourceConfig
+		err    string
+	}{
+		"new resource": {
+			state:  nil,
+			config: config("test", 1, []interface{}{datastore("1.0", "default")}, true),
+		},
+		"update in place": {
+			state:  state("true"),
+			config: config("test", 2, []interface{}{datastore("1.0", "custom")}, true),
+		},
+		"replace": {
+			state:  state("true"),
+			config: config("renamed", 1, []interface{}{datastore("1.0", "default")}, true),
+			err:    "mcs_test id can't be replaced while deletion_protection is enabled, changed attributes: name",
+		},
+		"replace by nested attribute": {
+			state:  state("true"),
+			config: config("renamed", 1, []interface{}{datastore("2.0", "default")}, true),
+			err: "mcs_test id can't be replaced while deletion_protection is enabled, " +
+				"changed attributes: datastore.0.version, name",
+		},
+		"replace by number of blocks": {
+			state:  state("true"),
+			config: config("test", 1, []interface{}{datastore("1.0", "default"), datastore("2.0", "")}, true),
+			err:    "mcs_test id can't be replaced while deletion_protection is enabled, changed attributes: datastore",
+		},
+		"protection disabled in the same plan": {
+			state:  state("true"),
+			config: config("renamed", 1, []interface{}{datastore("1.0", "default")}, false),
+			err:    "mcs_test id can't be replaced while deletion_protection is enabled, changed attributes: name",
+		},
+		"protection disabled": {
+			state:  state("false"),
+			config: config("renamed", 1, []interface{}{datastore("1.0", "default")}, false),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := r.Diff(tt.state, tt.config, nil)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -1,15 +1,15 @@
 package mcs
 
 import (
-	"context"
 	"fmt"
-	"net/http"
+	"log"
 	"os"
-	"time"
+	"sync"
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/utils/terraform/auth"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 	"github.com/hashicorp/terraform-plugin-sdk/meta"
 	"github.com/hashicorp/terraform-plugin-sdk/terraform"
 )
@@ -18,8 +18,14 @@ const (
 	maxRetriesCount         = 3
 	defaultIdentityEndpoint = "https://infra.mail.ru/identity/v3/"
 	defaultUsersDomainName  = "users"
-	requestsMaxRetriesCount = 3
-	requestsRetryDelay      = 30 * time.Millisecond
+	defaultRegionName       = "RegionOne"
+)
+
+// Service names used as endpoint_overrides keys.
+const (
+	identityService       = "identity"
+	containerInfraService = "container-infra"
+	databaseService       = "database"
 )
 
 // configer is interface to work with gophercloud.Config calls
@@ -28,12 +34,27 @@ type configer interface {
 	IdentityV3Client(region string) (ContainerClient, error)
 	ContainerInfraV1Client(region string) (ContainerClient, error)
 	DatabaseV1Client(region string) (ContainerClient, error)
+	ContainerInfraV1MicroVersion() (string, error)
 	GetRegion() string
 }
 
 // config uses openstackbase.Config as the base/foundation of this provider's
 type config struct {
 	auth.Config
+
+	// limiter applies per-service request limits, nil if there are none.
+	limiter *rateLimitRoundTripper
+	// containerInfraAPIVersion is the microversion pinned in the configuration.
+	containerInfraAPIVersion string
+	// containerInfraMicroVersion is negotiated with the container-infra service
+	// on the first use, so providers which don't use it don't request it.
+	containerInfraMicroVersionOnce sync.Once
+	containerInfraMicroVersion     string
+	containerInfraMicroVersionErr  error
+
+	// clients caches service clients by service and region.
+	clientsMu sync.Mutex
+	clients   map[string]*gophercloud.ServiceClient
 }
 
 var _ configer = &config{}
@@ -45,63 +66,161 @@ func (c *config) GetRegion() string {
 
 // IdentityV3Client is implementation of ContainerInfraV1Client method
 func (c *config) IdentityV3Client(region string) (ContainerClient, error) {
-	return c.Config.IdentityV3Client(region)
+	client, err := c.cachedClient(identityService, region, func() (*gophercloud.ServiceClient, error) {
+		return c.serviceClient(identityService, region, c.Config.IdentityV3Client)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
 }
 
 // ContainerInfraV1Client is implementation of ContainerInfraV1Client method
 func (c *config) ContainerInfraV1Client(region string) (ContainerClient, error) {
-	return c.Config.ContainerInfraV1Client(region)
+	version, err := c.ContainerInfraV1MicroVersion()
+	if err != nil {
+		return nil, err
+	}
+	client, err := c.cachedClient(containerInfraService, region, func() (*gophercloud.ServiceClient, error) {
+		client, err := c.serviceClient(containerInfraService, region, c.Config.ContainerInfraV1Client)
+		if err != nil {
+			return nil, err
+		}
+		addMicroVersionHeader(client, version)
+		return client, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// ContainerInfraV1MicroVersion returns container-infra API microversion used
+// by the provider, negotiating it on the first call.
+func (c *config) ContainerInfraV1MicroVersion() (string, error) {
+	c.containerInfraMicroVersionOnce.Do(func() {
+		c.containerInfraMicroVersion, c.containerInfraMicroVersionErr = c.negotiateContainerInfraMicroVersion()
+	})
+	return c.containerInfraMicroVersion, c.containerInfraMicroVersionErr
+}
+
+// negotiateContainerInfraMicroVersion discovers container-infra API
+// microversions supported in the provider region and picks one to use.
+func (c *config) negotiateContainerInfraMicroVersion() (string, error) {
+	var serverMin, serverMax microVersion
+	client, err := c.serviceClient(containerInfraService, c.Region, c.Config.ContainerInfraV1Client)
+	if err == nil {
+		serverMin, serverMax, err = supportedMicroVersions(client)
+	}
+	version, err := negotiateMicroVersion(c.containerInfraAPIVersion, serverMin, serverMax, err)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("[DEBUG] Using container-infra API microversion %s", version)
+	return version, nil
 }
 
 // DatabaseV1Client is implementation of DatabaseV1Client method
 func (c *config) DatabaseV1Client(region string) (ContainerClient, error) {
-	client, clientErr := c.Config.DatabaseV1Client(region)
-	client.ProviderClient.RetryFunc = func(context context.Context, method, url string, options *gophercloud.RequestOpts, err error, failCount uint) error {
-		if failCount >= requestsMaxRetriesCount {
-			return err
+	client, err := c.cachedClient(databaseService, region, func() (*gophercloud.ServiceClient, error) {
+		return c.serviceClient(databaseService, region, c.Config.DatabaseV1Client)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// cachedClient returns client of the service in the region, created by
+// newClient on the first call. Clients are shared by all resources.
+func (c *config) cachedClient(service, region string, newClient func() (*gophercloud.ServiceClient, error)) (*gophercloud.ServiceClient, error) {
+	key := service + "/" + region
+	c.clientsMu.Lock()
+	defer c.clientsMu.Unlock()
+	if client, ok := c.clients[key]; ok {
+		return client, nil
+	}
+	client, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+	if c.clients == nil {
+		c.clients = make(map[string]*gophercloud.ServiceClient)
+	}
+	c.clients[key] = client
+	return client, nil
+}
+
+// serviceClient returns client of the service in the region. An endpoint from
+// endpoint_overrides is used as is, without looking up the service catalog.
+func (c *config) serviceClient(service, region string, newClient func(string) (*gophercloud.ServiceClient, error)) (*gophercloud.ServiceClient, error) {
+	var client *gophercloud.ServiceClient
+	endpoint, ok := c.EndpointOverrides[service].(string)
+	if !ok || endpoint == "" {
+		var err error
+		if client, err = newClient(region); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := c.Authenticate(); err != nil {
+			return nil, err
 		}
-		switch errType := err.(type) {
-		case gophercloud.ErrDefault500, gophercloud.ErrDefault503:
-			time.Sleep(requestsRetryDelay)
-			return nil
-		case gophercloud.ErrUnexpectedResponseCode:
-			if errType.Actual == http.StatusGatewayTimeout {
-				time.Sleep(requestsRetryDelay)
-				return nil
-			}
-			return err
-		default:
-			return err
+		log.Printf("[DEBUG] MCS endpoint for %s is overridden: %s", service, endpoint)
+		client = &gophercloud.ServiceClient{
+			ProviderClient: c.OsClient,
+			Endpoint:       gophercloud.NormalizeURL(endpoint),
+			Type:           service,
 		}
 	}
-	return client, clientErr
+	if c.limiter != nil {
+		c.limiter.register(service, client.Endpoint)
+	}
+	return client, nil
 }
 
 func newConfig(d *schema.ResourceData, terraformVersion string) (configer, error) {
-	if os.Getenv("TF_ACC_MOCK_MCS") != "" {
-		return &dummyConfig{}, nil
-	}
-
 	config := &config{
-		auth.Config{
+		Config: auth.Config{
 			CACertFile:       d.Get("cacert_file").(string),
 			ClientCertFile:   d.Get("cert").(string),
 			ClientKeyFile:    d.Get("key").(string),
 			Password:         d.Get("password").(string),
 			TenantID:         d.Get("project_id").(string),
+			TenantName:       d.Get("project_name").(string),
 			Region:           d.Get("region").(string),
+			Cloud:            d.Get("cloud").(string),
+			Token:            d.Get("token").(string),
 			AllowReauth:      true,
 			MaxRetries:       maxRetriesCount,
 			TerraformVersion: terraformVersion,
 			SDKVersion:       meta.SDKVersionString(),
+
+			ApplicationCredentialID:     d.Get("application_credential_id").(string),
+			ApplicationCredentialName:   d.Get("application_credential_name").(string),
+			ApplicationCredentialSecret: d.Get("application_credential_secret").(string),
+
+			UserDomainID:      d.Get("user_domain_id").(string),
+			UserDomainName:    d.Get("user_domain_name").(string),
+			ProjectDomainName: d.Get("project_domain_name").(string),
 		},
 	}
 
-	if config.TenantID == "" {
+	// Either ID or name of the project and the user domain may be set, so env
+	// vars are used only if neither is set by the provider arguments.
+	if config.TenantID == "" && config.TenantName == "" {
 		config.TenantID = os.Getenv("OS_PROJECT_ID")
+		if config.TenantID == "" {
+			config.TenantName = os.Getenv("OS_PROJECT_NAME")
+		}
 	}
-	if config.UserDomainID == "" {
+	if config.UserDomainID == "" && config.UserDomainName == "" {
 		config.UserDomainID = os.Getenv("OS_USER_DOMAIN_ID")
+		if config.UserDomainID == "" {
+			config.UserDomainName = os.Getenv("OS_USER_DOMAIN_NAME")
+		}
+	}
+	if config.ProjectDomainName == "" {
+		config.ProjectDomainName = os.Getenv("OS_PROJECT_DOMAIN_NAME")
 	}
 	if config.Password == "" {
 		config.Password = os.Getenv("OS_PASSWORD")
@@ -112,30 +231,93 @@ func newConfig(d *schema.ResourceData, terraformVersion string) (configer, error
 	if config.Region == "" {
 		config.Region = os.Getenv("OS_REGION")
 	}
+	if config.ApplicationCredentialID == "" {
+		config.ApplicationCredentialID = os.Getenv("OS_APPLICATION_CREDENTIAL_ID")
+	}
+	if config.ApplicationCredentialName == "" {
+		config.ApplicationCredentialName = os.Getenv("OS_APPLICATION_CREDENTIAL_NAME")
+	}
+	if config.ApplicationCredentialSecret == "" {
+		config.ApplicationCredentialSecret = os.Getenv("OS_APPLICATION_CREDENTIAL_SECRET")
+	}
+	if config.Cloud == "" {
+		config.Cloud = os.Getenv("OS_CLOUD")
+	}
+	if config.Token == "" {
+		config.Token = os.Getenv("OS_TOKEN")
+	}
 
 	v, ok := d.GetOk("insecure")
 	if ok {
 		insecure := v.(bool)
 		config.Insecure = &insecure
 	}
+	if v, ok := d.GetOk("endpoint_overrides"); ok {
+		config.EndpointOverrides = v.(map[string]interface{})
+	}
 	v, ok = d.GetOk("auth_url")
 	if ok {
 		config.IdentityEndpoint = v.(string)
+	} else if identity, ok := config.EndpointOverrides[identityService].(string); ok && identity != "" {
+		config.IdentityEndpoint = identity
 	} else {
 		config.IdentityEndpoint = defaultIdentityEndpoint
 	}
-	if err := initWithUsername(d, config); err != nil {
+
+	var err error
+	switch {
+	case config.Cloud != "":
+		// Credentials, region and TLS settings are read from clouds.yaml
+		// and secure.yaml by LoadAndValidate.
+	case config.Token != "":
+		initWithToken(config)
+	case config.ApplicationCredentialID != "" || config.ApplicationCredentialName != "":
+		err = initWithApplicationCredential(d, config)
+	default:
+		err = initWithUsername(d, config)
+	}
+	if err != nil {
 		return nil, err
 	}
 
-	if err := config.LoadAndValidate(); err != nil {
+	retry, err := extractRetryPolicy(d)
+	if err != nil {
 		return nil, err
 	}
+
+	restoreEnv := func() {}
+	if config.Cloud == "" {
+		restoreEnv = hideOverriddenAuthEnv(config)
+	}
+	err = config.LoadAndValidate()
+	restoreEnv()
+	if err != nil {
+		return nil, err
+	}
+	// Retries and request tracing are handled by the provider client shared
+	// by all service clients.
+	retry.apply(config.OsClient)
+	config.OsClient.HTTPClient.Transport = newTraceRoundTripper(config.OsClient.HTTPClient.Transport)
+	if limits := extractServiceLimits(d); limits != nil {
+		config.limiter = newRateLimitRoundTripper(config.OsClient.HTTPClient.Transport, limits)
+		config.limiter.register(identityService, config.IdentityEndpoint)
+		config.OsClient.HTTPClient.Transport = config.limiter
+	}
+	if config.Region == "" {
+		config.Region = defaultRegionName
+	}
+	config.containerInfraAPIVersion = d.Get("container_infra_api_version").(string)
 	return config, nil
 }
 
+// initWithToken prepares config for authentication with a pre-issued token.
+func initWithToken(config *config) {
+	config.Username = ""
+	config.Password = ""
+}
+
 func initWithUsername(d *schema.ResourceData, config *config) error {
-	config.UserDomainName = defaultUsersDomainName
+	setDefaultUserDomain(config)
 
 	config.Username = os.Getenv("OS_USERNAME")
 	if v, ok := d.GetOk("username"); ok {
@@ -144,6 +326,81 @@ func initWithUsername(d *schema.ResourceData, config *config) error {
 	if config.Username == "" {
 		return fmt.Errorf("username must be specified")
 	}
+	if config.TenantID == "" && config.TenantName == "" {
+		return fmt.Errorf("project_id or project_name must be specified")
+	}
+	// Project name is unique only within its domain, which is the domain
+	// of the user unless set explicitly.
+	if config.TenantID == "" && config.ProjectDomainName == "" {
+		config.ProjectDomainName = config.UserDomainName
+		config.ProjectDomainID = config.UserDomainID
+	}
+	return nil
+}
+
+// hideOverriddenAuthEnv unsets OS_* env vars naming an entity which is set
+// by a provider argument in another way: gophercloud reads such env vars on
+// its own and rejects an entity with both ID and name. The returned function
+// restores the env vars.
+func hideOverriddenAuthEnv(config *config) func() {
+	overrides := []struct {
+		set      string
+		arg, env string
+	}{
+		{set: config.UserDomainName, arg: "user_domain_name", env: "OS_USER_DOMAIN_ID"},
+		{set: config.UserDomainID, arg: "user_domain_id", env: "OS_USER_DOMAIN_NAME"},
+		{set: config.TenantID, arg: "project_id", env: "OS_PROJECT_NAME"},
+		{set: config.TenantName, arg: "project_name", env: "OS_PROJECT_ID"},
+	}
+	hidden := make(map[string]string)
+	for _, o := range overrides {
+		if v, ok := os.LookupEnv(o.env); ok && o.set != "" {
+			log.Printf("[DEBUG] Ignoring %s environment variable in favor of %s", o.env, o.arg)
+			hidden[o.env] = v
+			os.Unsetenv(o.env)
+		}
+	}
+	return func() {
+		for name, v := range hidden {
+			os.Setenv(name, v)
+		}
+	}
+}
+
+// setDefaultUserDomain uses the MCS users domain unless a user domain is set.
+func setDefaultUserDomain(config *config) {
+	if config.UserDomainName == "" && config.UserDomainID == "" {
+		config.UserDomainName = defaultUsersDomainName
+	}
+}
+
+// initWithApplicationCredential prepares config for authentication with an
+// application credential. The credential is bound to a project, so neither
+// password nor project scope is sent.
+func initWithApplicationCredential(d *schema.ResourceData, config *config) error {
+	if config.ApplicationCredentialSecret == "" {
+		return fmt.Errorf("application_credential_secret must be specified")
+	}
+	config.Password = ""
+	config.TenantID = ""
+	config.TenantName = ""
+	config.ProjectDomainName = ""
+
+	if config.ApplicationCredentialID != "" {
+		config.Username = ""
+		return nil
+	}
+
+	// Application credential name is unique only within its owner, so the
+	// user has to be identified as well.
+	setDefaultUserDomain(config)
+	config.Username = os.Getenv("OS_USERNAME")
+	if v, ok := d.GetOk("username"); ok {
+		config.Username = v.(string)
+	}
+	if config.Username == "" {
+		return fmt.Errorf("username must be specified when application_credential_name is used")
+	}
 	return nil
 }
 
@@ -159,27 +416,53 @@ func Provider() terraform.ResourceProvider {
 			},
 			"project_id": {
 				Type:        schema.TypeString,
-				Required:    true,
+				Optional:    true,
 				DefaultFunc: schema.EnvDefaultFunc("PROJECT_ID", ""),
 				Description: "The ID of Project to login with.",
 			},
+			"project_name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("PROJECT_NAME", ""),
+				Description: "The name of Project to login with.",
+			},
+			"project_domain_name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("PROJECT_DOMAIN_NAME", ""),
+				Description: "The name of the domain of Project to login with.",
+			},
+			"user_domain_name": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				DefaultFunc:   schema.EnvDefaultFunc("USER_DOMAIN_NAME", nil),
+				ConflictsWith: []string{"user_domain_id"},
+				Description:   "The name of the domain of User to login with.",
+			},
+			"user_domain_id": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				DefaultFunc:   schema.EnvDefaultFunc("USER_DOMAIN_ID", nil),
+				ConflictsWith: []string{"user_domain_name"},
+				Description:   "The ID of the domain of User to login with.",
+			},
 			"password": {
 				Type:        schema.TypeString,
-				Required:    true,
+				Optional:    true,
 				Sensitive:   true,
 				DefaultFunc: schema.EnvDefaultFunc("PASSWORD", ""),
 				Description: "Password to login with.",
 			},
 			"username": {
 				Type:        schema.TypeString,
-				Required:    true,
+				Optional:    true,
 				DefaultFunc: schema.EnvDefaultFunc("USER_NAME", ""),
 				Description: "User name to login with.",
 			},
 			"region": {
 				Type:        schema.TypeString,
 				Optional:    true,
-				DefaultFunc: schema.EnvDefaultFunc("REGION", "RegionOne"),
+				DefaultFunc: schema.EnvDefaultFunc("REGION", ""),
 				Description: "A region to use.",
 			},
 			"insecure": {
@@ -206,28 +489,125 @@ func Provider() terraform.ResourceProvider {
 				DefaultFunc: schema.EnvDefaultFunc("KEY", ""),
 				Description: "A client private key to authenticate with.",
 			},
+			"token": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				DefaultFunc: schema.EnvDefaultFunc("TOKEN", ""),
+				Description: "A pre-issued token to authenticate with instead of username and password.",
+			},
+			"cloud": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("CLOUD", ""),
+				Description: "An entry in clouds.yaml to read credentials, region and TLS settings from.",
+			},
+			"endpoint_overrides": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Service endpoints to use instead of the ones from the catalog, keyed by service type.",
+			},
+			"container_infra_api_version": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Container-infra API microversion to use instead of the negotiated one.",
+			},
+			"max_concurrent_requests": {
+				Type:         schema.TypeMap,
+				Optional:     true,
+				Elem:         &schema.Schema{Type: schema.TypeInt},
+				ValidateFunc: validateServiceLimits,
+				Description:  "Maximum number of requests in flight, keyed by service type.",
+			},
+			"requests_per_second": {
+				Type:         schema.TypeMap,
+				Optional:     true,
+				Elem:         &schema.Schema{Type: schema.TypeInt},
+				ValidateFunc: validateServiceLimits,
+				Description:  "Maximum rate of requests, keyed by service type.",
+			},
+			"retry": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Retry policy for failed API requests.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"max_attempts": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      defaultRetryMaxAttempts,
+							ValidateFunc: validation.IntAtLeast(1),
+							Description:  "Maximum number of attempts for a request, including the first one.",
+						},
+						"base_delay": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      defaultRetryBaseDelay.String(),
+							ValidateFunc: validateDuration,
+							Description:  "Delay before the first retry; doubled on each next attempt.",
+						},
+						"max_delay": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      defaultRetryMaxDelay.String(),
+							ValidateFunc: validateDuration,
+							Description:  "Upper bound of delay between attempts.",
+						},
+						"retryable_status_codes": {
+							Type:        schema.TypeList,
+							Optional:    true,
+							Elem:        &schema.Schema{Type: schema.TypeInt},
+							Description: "HTTP status codes to retry. Default is 409, 429, 500, 503 and 504.",
+						},
+					},
+				},
+			},
+			"application_credential_id": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("APPLICATION_CREDENTIAL_ID", ""),
+				Description: "The ID of an application credential to authenticate with.",
+			},
+			"application_credential_name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("APPLICATION_CREDENTIAL_NAME", ""),
+				Description: "The name of an application credential to authenticate with. Requires username.",
+			},
+			"application_credential_secret": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				DefaultFunc: schema.EnvDefaultFunc("APPLICATION_CREDENTIAL_SECRET", ""),
+				Description: "The secret of an application credential to authenticate with.",
+			},
 		},
 
 		DataSourcesMap: map[string]*schema.Resource{
-			"mcs_kubernetes_clustertemplate":  dataSourceKubernetesClusterTemplate(),
-			"mcs_kubernetes_clustertemplates": dataSourceKubernetesClusterTemplates(),
-			"mcs_kubernetes_cluster":          dataSourceKubernetesCluster(),
-			"mcs_kubernetes_node_group":       dataSourceKubernetesNodeGroup(),
-			"mcs_db_instance":                 dataSourceDatabaseInstance(),
-			"mcs_db_user":                     dataSourceDatabaseUser(),
-			"mcs_db_database":                 dataSourceDatabaseDatabase(),
-			"mcs_region":                      dataSourceMcsRegion(),
-			"mcs_regions":                     dataSourceMcsRegions(),
+			"mcs_kubernetes_clustertemplate":      dataSourceKubernetesClusterTemplate(),
+			"mcs_kubernetes_clustertemplates":     dataSourceKubernetesClusterTemplates(),
+			"mcs_kubernetes_cluster":              dataSourceKubernetesCluster(),
+			"mcs_kubernetes_clusters":             dataSourceKubernetesClusters(),
+			"mcs_kubernetes_cluster_upgrade_path": dataSourceKubernetesClusterUpgradePath(),
+			"mcs_kubernetes_node_group":           dataSourceKubernetesNodeGroup(),
+			"mcs_db_instance":                     dataSourceDatabaseInstance(),
+			"mcs_db_user":                         dataSourceDatabaseUser(),
+			"mcs_db_database":                     dataSourceDatabaseDatabase(),
+			"mcs_region":                          dataSourceMcsRegion(),
+			"mcs_regions":                         dataSourceMcsRegions(),
 		},
 
 		ResourcesMap: map[string]*schema.Resource{
-			"mcs_kubernetes_cluster":     resourceKubernetesCluster(),
-			"mcs_kubernetes_node_group":  resourceKubernetesNodeGroup(),
-			"mcs_db_instance":            resourceDatabaseInstance(),
-			"mcs_db_user":                resourceDatabaseUser(),
-			"mcs_db_database":            resourceDatabaseDatabase(),
-			"mcs_db_cluster":             resourceDatabaseCluster(),
-			"mcs_db_cluster_with_shards": resourceDatabaseClusterWithShards(),
+			"mcs_kubernetes_cluster":         resourceKubernetesCluster(),
+			"mcs_kubernetes_clustertemplate": resourceKubernetesClusterTemplate(),
+			"mcs_kubernetes_node_group":      resourceKubernetesNodeGroup(),
+			"mcs_db_instance":                resourceDatabaseInstance(),
+			"mcs_db_user":                    resourceDatabaseUser(),
+			"mcs_db_database":                resourceDatabaseDatabase(),
+			"mcs_db_cluster":                 resourceDatabaseCluster(),
+			"mcs_db_cluster_with_shards":     resourceDatabaseClusterWithShards(),
 		},
 	}
 
@@ -1,40 +1,109 @@
 package mcs
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/pathorcontents"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+
+	"github.com/MailRuCloudSolutions/terraform-provider-mcs/mcs/internal/emulator"
 )
 
 var (
-	clusterTemplateID          = os.Getenv("CLUSTER_TEMPLATE_ID")
-	osFlavorID                 = os.Getenv("OS_FLAVOR_ID")
-	osNewFlavorID              = os.Getenv("OS_NEW_FLAVOR_ID")
-	osNetworkID                = os.Getenv("OS_NETWORK_ID")
-	osSubnetworkID             = os.Getenv("OS_SUBNETWORK_ID")
+	clusterTemplateID          = testAccEnv("CLUSTER_TEMPLATE_ID", emulator.ClusterTemplateID)
+	nextClusterTemplateID      = testAccEnv("NEXT_CLUSTER_TEMPLATE_ID", emulator.NextClusterTemplateID)
+	osFlavorID                 = testAccEnv("OS_FLAVOR_ID", "Standard-2-4-40")
+	osNewFlavorID              = testAccEnv("OS_NEW_FLAVOR_ID", "Standard-4-8-80")
+	osNetworkID                = testAccEnv("OS_NETWORK_ID", "emulated-network")
+	osSubnetworkID             = testAccEnv("OS_SUBNETWORK_ID", "emulated-subnet")
 	osRegionName               = os.Getenv("OS_REGION_NAME")
-	osKeypairName              = os.Getenv("OS_KEYPAIR_NAME")
-	osDBDatastoreVersion       = os.Getenv("OS_DB_DATASTORE_VERSION")
-	osDBDatastoreType          = os.Getenv("OS_DB_DATASTORE_TYPE")
-	osDBShardsDatastoreType    = os.Getenv("OS_DB_SHARDS_DATASTORE_TYPE")
-	osDBShardsDatastoreVersion = os.Getenv("OS_DB_SHARDS_DATASTORE_VERSION")
+	osKeypairName              = testAccEnv("OS_KEYPAIR_NAME", "emulated-keypair")
+	osImageID                  = testAccEnv("OS_IMAGE_ID", "emulated-image")
+	osDBDatastoreVersion       = testAccEnv("OS_DB_DATASTORE_VERSION", "13")
+	osDBDatastoreType          = testAccEnv("OS_DB_DATASTORE_TYPE", "postgresql")
+	osDBShardsDatastoreType    = testAccEnv("OS_DB_SHARDS_DATASTORE_TYPE", "clickhouse")
+	osDBShardsDatastoreVersion = testAccEnv("OS_DB_SHARDS_DATASTORE_VERSION", "20.8")
 )
 
+// testAccEnv returns the value of the env var, or the emulated value if
+// acceptance tests run against the MCS API emulator and the var is not set.
+func testAccEnv(name, emulated string) string {
+	if v := os.Getenv(name); v != "" || os.Getenv("TF_ACC_MOCK_MCS") == "" {
+		return v
+	}
+	return emulated
+}
+
 var testAccProviders map[string]terraform.ResourceProvider
 var testAccProvider *schema.Provider
 
 func init() {
 	testAccProvider = Provider().(*schema.Provider)
+	if os.Getenv("TF_ACC_MOCK_MCS") != "" {
+		useEmulator(testAccProvider)
+	}
 	testAccProviders = map[string]terraform.ResourceProvider{
 		"mcs": testAccProvider,
 	}
 }
 
+// useEmulator points the provider to the in-process MCS API emulator used to
+// run acceptance tests offline and shortens state change intervals to match
+// the emulator. The emulator accepts any credentials, so the ones from the
+// environment are ignored.
+func useEmulator(p *schema.Provider) {
+	for _, name := range []string{
+		"OS_CLOUD", "OS_TOKEN", "OS_AUTH_TOKEN",
+		"OS_APPLICATION_CREDENTIAL_ID", "OS_APPLICATION_CREDENTIAL_NAME", "OS_APPLICATION_CREDENTIAL_SECRET",
+	} {
+		os.Unsetenv(name)
+	}
+
+	createUpdateDelay = time.Second
+	createUpdatePollInterval = time.Second
+	deleteDelay = time.Second
+	nodeGroupDeleteDelay = time.Second
+	deletePollInterval = time.Second
+	dbInstanceDelay = time.Second
+	dbInstanceMinTimeout = time.Second
+	dbDatabaseDelay = time.Second
+	dbDatabaseMinTimeout = time.Second
+	dbUserDelay = time.Second
+	dbUserMinTimeout = time.Second
+
+	configure := p.ConfigureFunc
+	p.ConfigureFunc = func(d *schema.ResourceData) (interface{}, error) {
+		settings := map[string]interface{}{
+			"auth_url":                      emulator.Shared().IdentityEndpoint(),
+			"username":                      "emulated",
+			"password":                      "emulated",
+			"project_id":                    emulator.ProjectID,
+			"project_name":                  "",
+			"cloud":                         "",
+			"token":                         "",
+			"application_credential_id":     "",
+			"application_credential_name":   "",
+			"application_credential_secret": "",
+			"endpoint_overrides":            nil,
+		}
+		for k, v := range settings {
+			if err := d.Set(k, v); err != nil {
+				return nil, err
+			}
+		}
+		return configure(d)
+	}
+}
+
 func testAccPreCheckDatabase(t *testing.T) {
 	vars := map[string]interface{}{
 		"OS_NETWORK_ID":           osNetworkID,
@@ -64,6 +133,12 @@ func testAccPreCheckKubernetes(t *testing.T) {
 	}
 }
 
+func testAccPreCheckKubernetesClusterTemplate(t *testing.T) {
+	if osImageID == "" {
+		t.Fatalf("'OS_IMAGE_ID' must be set for acceptance test")
+	}
+}
+
 func TestProvider(t *testing.T) {
 	if err := Provider().(*schema.Provider).InternalValidate(); err != nil {
 		t.Fatalf("err: %s", err)
@@ -188,6 +263,511 @@ func TestAccProvider_clientCertString(t *testing.T) {
 	}
 }
 
+// identityStandIn is a minimal local Identity v3 service which issues a token
+// for any request and remembers the auth methods it was asked to use.
+type identityStandIn struct {
+	*httptest.Server
+	methods    []string
+	userDomain standInName
+	project    standInName
+}
+
+// standInName is an ID or name of an identity entity with its domain.
+type standInName struct {
+	ID     string       `json:"id"`
+	Name   string       `json:"name"`
+	Domain *standInName `json:"domain"`
+}
+
+func newIdentityStandIn(t *testing.T) *identityStandIn {
+	s := &identityStandIn{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v3/auth/tokens" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var req struct {
+			Auth struct {
+				Identity struct {
+					Methods  []string `json:"methods"`
+					Password struct {
+						User standInName `json:"user"`
+					} `json:"password"`
+				} `json:"identity"`
+				Scope struct {
+					Project standInName `json:"project"`
+				} `json:"scope"`
+			} `json:"auth"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode auth request: %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.methods = req.Auth.Identity.Methods
+		if d := req.Auth.Identity.Password.User.Domain; d != nil {
+			s.userDomain = *d
+		}
+		s.project = req.Auth.Scope.Project
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Subject-Token", "stand-in-token")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"token": {"expires_at": "2100-01-01T00:00:00.000000Z", "catalog": []}}`)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func unsetAuthEnv(t *testing.T) {
+	for _, name := range []string{
+		"OS_USERNAME", "OS_PASSWORD", "OS_PROJECT_ID", "OS_USER_DOMAIN_ID",
+		"OS_USER_DOMAIN_NAME", "OS_PROJECT_NAME", "OS_PROJECT_DOMAIN_NAME",
+		"USER_DOMAIN_ID", "USER_DOMAIN_NAME", "PROJECT_NAME", "PROJECT_DOMAIN_NAME",
+		"OS_APPLICATION_CREDENTIAL_ID", "OS_APPLICATION_CREDENTIAL_NAME", "OS_APPLICATION_CREDENTIAL_SECRET",
+		"OS_CLOUD", "OS_TOKEN", "OS_AUTH_TOKEN", "OS_REGION", "OS_CLIENT_CONFIG_FILE",
+		"USER_NAME", "PASSWORD", "PROJECT_ID", "REGION", "TOKEN", "CLOUD",
+		"APPLICATION_CREDENTIAL_ID", "APPLICATION_CREDENTIAL_NAME", "APPLICATION_CREDENTIAL_SECRET",
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestAccProvider_passwordAuth(t *testing.T) {
+	unsetAuthEnv(t)
+	identity := newIdentityStandIn(t)
+
+	raw := map[string]interface{}{
+		"auth_url":   identity.URL + "/v3/",
+		"username":   "user",
+		"password":   "secret",
+		"project_id": "project",
+	}
+
+	p := Provider()
+	if err := p.Configure(terraform.NewResourceConfigRaw(raw)); err != nil {
+		t.Fatalf("unexpected err when authenticating with password: %s", err)
+	}
+	if len(identity.methods) != 1 || identity.methods[0] != "password" {
+		t.Fatalf("expected password auth method, got %v", identity.methods)
+	}
+}
+
+func TestAccProvider_domains(t *testing.T) {
+	cases := []struct {
+		name       string
+		args       map[string]interface{}
+		env        map[string]string
+		userDomain standInName
+		project    standInName
+	}{
+		{
+			name:       "defaults",
+			args:       map[string]interface{}{"project_id": "project"},
+			userDomain: standInName{Name: "users"},
+			project:    standInName{ID: "project"},
+		},
+		{
+			name:       "user domain from env",
+			args:       map[string]interface{}{"project_id": "project"},
+			env:        map[string]string{"OS_USER_DOMAIN_NAME": "env"},
+			userDomain: standInName{Name: "env"},
+			project:    standInName{ID: "project"},
+		},
+		{
+			name:       "user domain name argument over env",
+			args:       map[string]interface{}{"project_id": "project", "user_domain_name": "federated"},
+			env:        map[string]string{"OS_USER_DOMAIN_NAME": "env"},
+			userDomain: standInName{Name: "federated"},
+			project:    standInName{ID: "project"},
+		},
+		{
+			name:       "user domain id argument",
+			args:       map[string]interface{}{"project_id": "project", "user_domain_id": "federated-id"},
+			userDomain: standInName{ID: "federated-id"},
+			project:    standInName{ID: "project"},
+		},
+		{
+			name:       "project id argument over env",
+			args:       map[string]interface{}{"project_id": "project"},
+			env:        map[string]string{"OS_PROJECT_ID": "env"},
+			userDomain: standInName{Name: "users"},
+			project:    standInName{ID: "project"},
+		},
+		{
+			name: "project name",
+			args: map[string]interface{}{
+				"project_name":        "project",
+				"project_domain_name": "projects",
+				"user_domain_name":    "federated",
+			},
+			userDomain: standInName{Name: "federated"},
+			project:    standInName{Name: "project", Domain: &standInName{Name: "projects"}},
+		},
+		{
+			name:       "project name in user domain",
+			args:       map[string]interface{}{"project_name": "project"},
+			userDomain: standInName{Name: "users"},
+			project:    standInName{Name: "project", Domain: &standInName{Name: "users"}},
+		},
+		{
+			name:       "project name from env",
+			args:       map[string]interface{}{},
+			env:        map[string]string{"OS_PROJECT_NAME": "env", "OS_PROJECT_DOMAIN_NAME": "projects"},
+			userDomain: standInName{Name: "users"},
+			project:    standInName{Name: "env", Domain: &standInName{Name: "projects"}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			unsetAuthEnv(t)
+			for k, v := range c.env {
+				t.Setenv(k, v)
+			}
+			identity := newIdentityStandIn(t)
+
+			raw := map[string]interface{}{
+				"auth_url": identity.URL + "/v3/",
+				"username": "user",
+				"password": "secret",
+			}
+			for k, v := range c.args {
+				raw[k] = v
+			}
+			if err := Provider().Configure(terraform.NewResourceConfigRaw(raw)); err != nil {
+				t.Fatalf("unexpected err when configuring: %s", err)
+			}
+			if identity.userDomain.ID != c.userDomain.ID || identity.userDomain.Name != c.userDomain.Name {
+				t.Fatalf("expected user domain %+v, got %+v", c.userDomain, identity.userDomain)
+			}
+			if identity.project.ID != c.project.ID || identity.project.Name != c.project.Name {
+				t.Fatalf("expected project %+v, got %+v", c.project, identity.project)
+			}
+			if c.project.Domain != nil && (identity.project.Domain == nil || *identity.project.Domain != *c.project.Domain) {
+				t.Fatalf("expected project domain %+v, got %+v", c.project.Domain, identity.project.Domain)
+			}
+		})
+	}
+}
+
+func TestAccProvider_domainEnvOverridden(t *testing.T) {
+	unsetAuthEnv(t)
+	t.Setenv("OS_USER_DOMAIN_ID", "env-id")
+	identity := newIdentityStandIn(t)
+
+	raw := map[string]interface{}{
+		"auth_url":         identity.URL + "/v3/",
+		"username":         "user",
+		"password":         "secret",
+		"project_id":       "project",
+		"user_domain_name": "federated",
+	}
+	if err := Provider().Configure(terraform.NewResourceConfigRaw(raw)); err != nil {
+		t.Fatalf("unexpected err when configuring: %s", err)
+	}
+	if identity.userDomain.ID != "" || identity.userDomain.Name != "federated" {
+		t.Fatalf("expected user domain federated, got %+v", identity.userDomain)
+	}
+	if v := os.Getenv("OS_USER_DOMAIN_ID"); v != "env-id" {
+		t.Fatalf("expected OS_USER_DOMAIN_ID to be restored, got %q", v)
+	}
+}
+
+func TestAccProvider_applicationCredentialAuth(t *testing.T) {
+	unsetAuthEnv(t)
+	identity := newIdentityStandIn(t)
+
+	raw := map[string]interface{}{
+		"auth_url":                      identity.URL + "/v3/",
+		"application_credential_id":     "credential",
+		"application_credential_secret": "secret",
+	}
+
+	p := Provider()
+	if err := p.Configure(terraform.NewResourceConfigRaw(raw)); err != nil {
+		t.Fatalf("unexpected err when authenticating with application credential: %s", err)
+	}
+	if len(identity.methods) != 1 || identity.methods[0] != "application_credential" {
+		t.Fatalf("expected application_credential auth method, got %v", identity.methods)
+	}
+}
+
+func TestAccProvider_applicationCredentialWithoutSecret(t *testing.T) {
+	unsetAuthEnv(t)
+	identity := newIdentityStandIn(t)
+
+	raw := map[string]interface{}{
+		"auth_url":                  identity.URL + "/v3/",
+		"application_credential_id": "credential",
+	}
+
+	p := Provider()
+	if err := p.Configure(terraform.NewResourceConfigRaw(raw)); err == nil {
+		t.Fatal("expected error when application_credential_secret is missing")
+	}
+}
+
+func TestAccProvider_tokenAuth(t *testing.T) {
+	unsetAuthEnv(t)
+	identity := newIdentityStandIn(t)
+
+	raw := map[string]interface{}{
+		"auth_url":   identity.URL + "/v3/",
+		"token":      "pre-issued-token",
+		"project_id": "project",
+	}
+
+	p := Provider()
+	if err := p.Configure(terraform.NewResourceConfigRaw(raw)); err != nil {
+		t.Fatalf("unexpected err when authenticating with token: %s", err)
+	}
+	if len(identity.methods) != 1 || identity.methods[0] != "token" {
+		t.Fatalf("expected token auth method, got %v", identity.methods)
+	}
+}
+
+func TestAccProvider_cloudsYAML(t *testing.T) {
+	unsetAuthEnv(t)
+	identity := newIdentityStandIn(t)
+
+	dir := t.TempDir()
+	cloudsYAML := fmt.Sprintf(`
+clouds:
+  standin:
+    auth:
+      auth_url: %s/v3/
+      username: user
+      project_id: project
+      user_domain_name: users
+    region_name: RegionTwo
+`, identity.URL)
+	secureYAML := `
+clouds:
+  standin:
+    auth:
+      password: secret
+`
+	if err := ioutil.WriteFile(dir+"/clouds.yaml", []byte(cloudsYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(dir+"/secure.yaml", []byte(secureYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// secure.yaml is looked up in the working directory first.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	raw := map[string]interface{}{
+		"cloud": "standin",
+	}
+
+	p := Provider().(*schema.Provider)
+	if err := p.Configure(terraform.NewResourceConfigRaw(raw)); err != nil {
+		t.Fatalf("unexpected err when configuring from clouds.yaml: %s", err)
+	}
+	if len(identity.methods) != 1 || identity.methods[0] != "password" {
+		t.Fatalf("expected password auth method, got %v", identity.methods)
+	}
+	if region := p.Meta().(configer).GetRegion(); region != "RegionTwo" {
+		t.Fatalf("expected region from clouds.yaml, got %s", region)
+	}
+}
+
+func TestAccProvider_endpointOverrides(t *testing.T) {
+	unsetAuthEnv(t)
+	identity := newIdentityStandIn(t)
+
+	var apiVersion string
+	containerInfra := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiVersion = r.Header.Get("MCS-API-Version")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"uuid": "cluster"}`)
+	}))
+	defer containerInfra.Close()
+
+	raw := map[string]interface{}{
+		"username":   "user",
+		"password":   "secret",
+		"project_id": "project",
+		"endpoint_overrides": map[string]interface{}{
+			"identity":        identity.URL + "/v3/",
+			"container-infra": containerInfra.URL,
+		},
+	}
+
+	p := Provider().(*schema.Provider)
+	if err := p.Configure(terraform.NewResourceConfigRaw(raw)); err != nil {
+		t.Fatalf("unexpected err when configuring with endpoint overrides: %s", err)
+	}
+	config := p.Meta().(configer)
+
+	client, err := config.ContainerInfraV1Client("")
+	if err != nil {
+		t.Fatalf("unexpected err when creating container infra client: %s", err)
+	}
+	c, err := clusterGet(client, "cluster").Extract()
+	if err != nil {
+		t.Fatalf("unexpected err when requesting overridden endpoint: %s", err)
+	}
+	if c.UUID != "cluster" {
+		t.Fatalf("unexpected cluster: %#v", c)
+	}
+	if apiVersion != "container-infra "+magnumAPIMicroVersion {
+		t.Fatalf("unexpected MCS-API-Version header: %q", apiVersion)
+	}
+
+	// The stand-in catalog is empty, so services without override are not found.
+	if _, err := config.DatabaseV1Client(""); err == nil {
+		t.Fatal("expected error for database service missing in catalog")
+	}
+}
+
+func TestAccProvider_containerInfraMicroVersion(t *testing.T) {
+	unsetAuthEnv(t)
+	identity := newIdentityStandIn(t)
+
+	var apiVersion string
+	var discoveries int
+	containerInfra := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/" {
+			discoveries++
+			w.WriteHeader(http.StatusMultipleChoices)
+			fmt.Fprint(w, `{"versions": [{"id": "v1", "min_version": "1.1", "max_version": "1.20"}]}`)
+			return
+		}
+		apiVersion = r.Header.Get("MCS-API-Version")
+		fmt.Fprint(w, `{"uuid": "cluster"}`)
+	}))
+	defer containerInfra.Close()
+
+	configure := func(pinned string) (configer, error) {
+		raw := map[string]interface{}{
+			"username":   "user",
+			"password":   "secret",
+			"project_id": "project",
+			"endpoint_overrides": map[string]interface{}{
+				"identity":        identity.URL + "/v3/",
+				"container-infra": containerInfra.URL + "/v1/",
+			},
+		}
+		if pinned != "" {
+			raw["container_infra_api_version"] = pinned
+		}
+		p := Provider().(*schema.Provider)
+		if err := p.Configure(terraform.NewResourceConfigRaw(raw)); err != nil {
+			return nil, err
+		}
+		return p.Meta().(configer), nil
+	}
+
+	config, err := configure("")
+	if err != nil {
+		t.Fatalf("unexpected err when configuring: %s", err)
+	}
+	// The microversion is negotiated on the first use only.
+	if discoveries != 0 {
+		t.Fatalf("expected no version discovery when configuring, got %d", discoveries)
+	}
+	client, err := config.ContainerInfraV1Client("")
+	if err != nil {
+		t.Fatalf("unexpected err when creating container infra client: %s", err)
+	}
+	if _, err := clusterGet(client, "cluster").Extract(); err != nil {
+		t.Fatalf("unexpected err when requesting cluster: %s", err)
+	}
+	if apiVersion != "container-infra 1.20" {
+		t.Fatalf("unexpected MCS-API-Version header: %q", apiVersion)
+	}
+	if v, err := config.ContainerInfraV1MicroVersion(); err != nil || v != "1.20" {
+		t.Fatalf("expected negotiated microversion 1.20, got %s: %v", v, err)
+	}
+	if discoveries != 1 {
+		t.Fatalf("expected a single version discovery, got %d", discoveries)
+	}
+
+	config, err = configure("1.10")
+	if err != nil {
+		t.Fatalf("unexpected err when configuring with pinned microversion: %s", err)
+	}
+	if v, err := config.ContainerInfraV1MicroVersion(); err != nil || v != "1.10" {
+		t.Fatalf("expected pinned microversion 1.10, got %s: %v", v, err)
+	}
+
+	config, err = configure("1.22")
+	if err != nil {
+		t.Fatalf("unexpected err when configuring with unsupported microversion: %s", err)
+	}
+	if _, err := config.ContainerInfraV1Client(""); err == nil {
+		t.Fatal("expected error for microversion not supported by the server")
+	}
+}
+
+func TestAccProvider_cachedClients(t *testing.T) {
+	unsetAuthEnv(t)
+	identity := newIdentityStandIn(t)
+
+	raw := map[string]interface{}{
+		"username":   "user",
+		"password":   "secret",
+		"project_id": "project",
+		"endpoint_overrides": map[string]interface{}{
+			"identity":        identity.URL + "/v3/",
+			"container-infra": "https://container.example.com/v1/",
+			"database":        "https://database.example.com/v1.0/project/",
+		},
+	}
+	p := Provider().(*schema.Provider)
+	if err := p.Configure(terraform.NewResourceConfigRaw(raw)); err != nil {
+		t.Fatalf("unexpected err when configuring: %s", err)
+	}
+	config := p.Meta().(configer)
+
+	const resources = 32
+	clients := make([]ContainerClient, resources)
+	var wg sync.WaitGroup
+	for i := 0; i < resources; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			if i%2 == 0 {
+				clients[i], err = config.ContainerInfraV1Client("RegionOne")
+			} else {
+				clients[i], err = config.DatabaseV1Client("RegionOne")
+			}
+			if err != nil {
+				t.Errorf("unexpected err when creating client: %s", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 2; i < resources; i++ {
+		if clients[i] != clients[i%2] {
+			t.Fatalf("resource %d got a client different from the shared one", i)
+		}
+	}
+	if clients[0] == clients[1] {
+		t.Fatal("container-infra and database services share a client")
+	}
+
+	other, err := config.ContainerInfraV1Client("RegionTwo")
+	if err != nil {
+		t.Fatalf("unexpected err when creating client: %s", err)
+	}
+	if other == clients[0] {
+		t.Fatal("different regions share a client")
+	}
+}
+
 func envVarContents(varName string) (string, error) {
 	// TODO(irlndts): the function is deprecated, replace it.
 	// nolint:staticcheck
@@ -0,0 +1,198 @@
+package mcs
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+// serviceLimit caps requests to a single service.
+type serviceLimit struct {
+	// sem holds a slot for every request in flight; nil means no cap.
+	sem chan struct{}
+	// interval is the minimum time between starts of requests.
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newServiceLimit(maxConcurrent, perSecond int) *serviceLimit {
+	l := &serviceLimit{}
+	if maxConcurrent > 0 {
+		l.sem = make(chan struct{}, maxConcurrent)
+	}
+	if perSecond > 0 {
+		l.interval = time.Second / time.Duration(perSecond)
+	}
+	return l
+}
+
+// acquire blocks until the request is allowed to start.
+func (l *serviceLimit) acquire(req *http.Request) error {
+	ctx := req.Context()
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if l.interval == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.release()
+		return ctx.Err()
+	}
+}
+
+func (l *serviceLimit) release() {
+	if l.sem != nil {
+		<-l.sem
+	}
+}
+
+// rateLimitRoundTripper applies per-service limits to requests. The service
+// of a request is found by the endpoint its URL starts with.
+type rateLimitRoundTripper struct {
+	rt     http.RoundTripper
+	limits map[string]*serviceLimit
+
+	mu        sync.RWMutex
+	endpoints map[string]string
+}
+
+func newRateLimitRoundTripper(rt http.RoundTripper, limits map[string]*serviceLimit) *rateLimitRoundTripper {
+	return &rateLimitRoundTripper{
+		rt:        rt,
+		limits:    limits,
+		endpoints: make(map[string]string),
+	}
+}
+
+// register binds endpoint to service, so requests to it obey the service limits.
+func (t *rateLimitRoundTripper) register(service, endpoint string) {
+	if t.limits[service] == nil || endpoint == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.endpoints[endpoint] = service
+}
+
+// limitFor returns limits of the service with the longest endpoint matching url.
+func (t *rateLimitRoundTripper) limitFor(url string) *serviceLimit {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var service, matched string
+	for endpoint, s := range t.endpoints {
+		if strings.HasPrefix(url, endpoint) && len(endpoint) > len(matched) {
+			service, matched = s, endpoint
+		}
+	}
+	return t.limits[service]
+}
+
+// RoundTrip waits for the service limits and performs the request. The
+// concurrency slot is held until the response body is read to the end or
+// closed.
+func (t *rateLimitRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	limit := t.limitFor(req.URL.String())
+	if limit == nil {
+		return t.rt.RoundTrip(req)
+	}
+	if err := limit.acquire(req); err != nil {
+		return nil, err
+	}
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		limit.release()
+		return resp, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: limit.release}
+	return resp, nil
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// Read releases the slot once the body is exhausted or fails, so a body
+// which is never closed does not hold the slot forever.
+func (b *releasingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// extractServiceLimits builds limits from max_concurrent_requests and
+// requests_per_second provider arguments. It returns nil if neither is set.
+func extractServiceLimits(d *schema.ResourceData) map[string]*serviceLimit {
+	concurrent := d.Get("max_concurrent_requests").(map[string]interface{})
+	perSecond := d.Get("requests_per_second").(map[string]interface{})
+	if len(concurrent) == 0 && len(perSecond) == 0 {
+		return nil
+	}
+	limits := make(map[string]*serviceLimit)
+	for _, service := range []string{identityService, containerInfraService, databaseService} {
+		c, _ := concurrent[service].(int)
+		r, _ := perSecond[service].(int)
+		if c > 0 || r > 0 {
+			limits[service] = newServiceLimit(c, r)
+		}
+	}
+	return limits
+}
+
+// validateServiceLimits checks that a per-service limits map is keyed by
+// known services and holds positive values.
+func validateServiceLimits(v interface{}, k string) (warns []string, errs []error) {
+	for service, value := range v.(map[string]interface{}) {
+		switch service {
+		case identityService, containerInfraService, databaseService:
+		default:
+			errs = append(errs, fmt.Errorf("%q contains unknown service %q, expected one of %s, %s, %s",
+				k, service, identityService, containerInfraService, databaseService))
+			continue
+		}
+		if n, err := strconv.Atoi(fmt.Sprint(value)); err != nil || n < 1 {
+			errs = append(errs, fmt.Errorf("%q value for %s must be a positive integer, got: %v", k, service, value))
+		}
+	}
+	return
+}
@@ -0,0 +1,112 @@
+package mcs
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitRoundTripperCapsConcurrency(t *testing.T) {
+	var inFlight, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+	}))
+	defer server.Close()
+
+	limiter := newRateLimitRoundTripper(http.DefaultTransport, map[string]*serviceLimit{
+		databaseService: newServiceLimit(2, 0),
+	})
+	limiter.register(databaseService, server.URL+"/database/")
+	client := &http.Client{Transport: limiter}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(server.URL + "/database/instances")
+			if assert.NoError(t, err) {
+				_, _ = ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 2, atomic.LoadInt32(&peak))
+}
+
+func TestRateLimitRoundTripperReleasesReadBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("kubeconfig"))
+	}))
+	defer server.Close()
+
+	limiter := newRateLimitRoundTripper(http.DefaultTransport, map[string]*serviceLimit{
+		containerInfraService: newServiceLimit(2, 0),
+	})
+	limiter.register(containerInfraService, server.URL+"/container-infra/")
+	client := &http.Client{Transport: limiter, Timeout: 5 * time.Second}
+
+	// Bodies are read to the end but never closed.
+	for i := 0; i < 5; i++ {
+		resp, err := client.Get(server.URL + "/container-infra/clusters/id/kube_config")
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, err = ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+	}
+}
+
+func TestRateLimitRoundTripperLimitsRate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	limiter := newRateLimitRoundTripper(http.DefaultTransport, map[string]*serviceLimit{
+		containerInfraService: newServiceLimit(0, 20),
+	})
+	limiter.register(containerInfraService, server.URL+"/container/")
+	client := &http.Client{Transport: limiter}
+
+	get := func(path string) {
+		resp, err := client.Get(server.URL + path)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+		}
+	}
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		get("/container/clusters")
+	}
+	// The first request starts at once, the other four are 50ms apart.
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(200*time.Millisecond))
+
+	start = time.Now()
+	for i := 0; i < 5; i++ {
+		get("/database/instances")
+	}
+	assert.Less(t, int64(time.Since(start)), int64(200*time.Millisecond), "unregistered endpoints are not limited")
+}
+
+func TestValidateServiceLimits(t *testing.T) {
+	_, errs := validateServiceLimits(map[string]interface{}{"database": 4, "identity": "2"}, "max_concurrent_requests")
+	assert.Empty(t, errs)
+
+	_, errs = validateServiceLimits(map[string]interface{}{"compute": 4, "database": 0}, "max_concurrent_requests")
+	assert.Len(t, errs, 2)
+}
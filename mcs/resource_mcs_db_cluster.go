@@ -48,6 +48,7 @@ func resourceDatabaseCluster() *schema.Resource {
 				}
 				d.Set("capabilities", flattenDatabaseInstanceCapabilities(capabilities))
 				d.Set("volume_type", dbImportedStatus)
+				d.Set("deletion_protection", false)
 				if v, ok := d.GetOk("wal_volume"); ok {
 					walV, _ := extractDatabaseWalVolume(v.([]interface{}))
 					walvolume := walVolume{Size: &walV.Size, VolumeType: dbImportedStatus}
@@ -62,6 +63,8 @@ func resourceDatabaseCluster() *schema.Resource {
 			Delete: schema.DefaultTimeout(dbDeleteTimeout),
 		},
 
+		CustomizeDiff: resourceDatabaseClusterCustomizeDiff,
+
 		Schema: map[string]*schema.Schema{
 			"region": {
 				Type:     schema.TypeString,
@@ -268,6 +271,8 @@ func resourceDatabaseCluster() *schema.Resource {
 					},
 				},
 			},
+
+			"deletion_protection": deletionProtectionSchema(),
 		},
 	}
 }
@@ -365,7 +370,7 @@ func resourceDatabaseClusterCreate(d *schema.ResourceData, meta interface{}) err
 		checkCapabilities = nil
 	}
 
-	log.Printf("[DEBUG] mcs_db_cluster create options: %#v", createOpts)
+	log.Printf("[DEBUG] Creating mcs_db_cluster")
 	clust := dbCluster{}
 	clust.Cluster = createOpts
 
@@ -419,7 +424,7 @@ func resourceDatabaseClusterRead(d *schema.ResourceData, meta interface{}) error
 		return checkDeleted(d, err, "Error retrieving mcs_db_cluster")
 	}
 
-	log.Printf("[DEBUG] Retrieved mcs_db_cluster %s: %#v", d.Id(), cluster)
+	log.Printf("[DEBUG] Retrieved mcs_db_cluster %s", d.Id())
 
 	d.Set("name", cluster.Name)
 	d.Set("datastore", flattenDatabaseInstanceDatastore(*cluster.DataStore))
@@ -720,7 +725,17 @@ func resourceDatabaseClusterUpdate(d *schema.ResourceData, meta interface{}) err
 	return resourceDatabaseClusterRead(d, meta)
 }
 
+// resourceDatabaseClusterCustomizeDiff prevents replacing the cluster with
+// deletion protection enabled.
+func resourceDatabaseClusterCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	return customizeDiffDeletionProtection(d, resourceDatabaseCluster().Schema, "mcs_db_cluster")
+}
+
 func resourceDatabaseClusterDelete(d *schema.ResourceData, meta interface{}) error {
+	if err := checkDeletionProtection(d, "mcs_db_cluster"); err != nil {
+		return err
+	}
+
 	config := meta.(configer)
 	DatabaseV1Client, err := config.DatabaseV1Client(getRegion(d, config))
 	if err != nil {
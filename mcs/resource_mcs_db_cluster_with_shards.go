@@ -392,7 +392,7 @@ func resourceDatabaseClusterWithShardsCreate(d *schema.ResourceData, meta interf
 		checkCapabilities = nil
 	}
 
-	log.Printf("[DEBUG] mcs_db_cluster_with_shards create options: %#v", createOpts)
+	log.Printf("[DEBUG] Creating mcs_db_cluster_with_shards")
 	clust := dbCluster{}
 	clust.Cluster = createOpts
 
@@ -446,7 +446,7 @@ func resourceDatabaseClusterWithShardsRead(d *schema.ResourceData, meta interfac
 		return checkDeleted(d, err, "error retrieving mcs_db_cluster_with_shards")
 	}
 
-	log.Printf("[DEBUG] Retrieved mcs_db_cluster_with_shards %s: %#v", d.Id(), cluster)
+	log.Printf("[DEBUG] Retrieved mcs_db_cluster_with_shards %s", d.Id())
 
 	d.Set("name", cluster.Name)
 	d.Set("datastore", flattenDatabaseInstanceDatastore(*cluster.DataStore))
@@ -9,14 +9,19 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 )
 
+// Dbaas state change intervals, shortened by tests run against the MCS API
+// emulator.
+var (
+	dbInstanceDelay      = 10 * time.Second
+	dbInstanceMinTimeout = 3 * time.Second
+	dbDatabaseDelay      = 10 * time.Second
+	dbDatabaseMinTimeout = 3 * time.Second
+	dbUserDelay          = 10 * time.Second
+	dbUserMinTimeout     = 3 * time.Second
+)
+
 // Dbaas timeouts
 const (
-	dbInstanceDelay         = 10 * time.Second
-	dbInstanceMinTimeout    = 3 * time.Second
-	dbDatabaseDelay         = 10 * time.Second
-	dbDatabaseMinTimeout    = 3 * time.Second
-	dbUserDelay             = 10 * time.Second
-	dbUserMinTimeout        = 3 * time.Second
 	dbCreateTimeout         = 30 * time.Minute
 	dbDeleteTimeout         = 30 * time.Minute
 	dbUserCreateTimeout     = 10 * time.Minute
@@ -74,6 +79,7 @@ func resourceDatabaseInstance() *schema.Resource {
 				}
 				d.Set("capabilities", flattenDatabaseInstanceCapabilities(capabilities))
 				d.Set("volume_type", dbImportedStatus)
+				d.Set("deletion_protection", false)
 				if v, ok := d.GetOk("wal_volume"); ok {
 					walV, _ := extractDatabaseWalVolume(v.([]interface{}))
 					walvolume := walVolume{Size: &walV.Size, VolumeType: dbImportedStatus}
@@ -89,6 +95,8 @@ func resourceDatabaseInstance() *schema.Resource {
 			Delete: schema.DefaultTimeout(dbDeleteTimeout),
 		},
 
+		CustomizeDiff: resourceDatabaseInstanceCustomizeDiff,
+
 		Schema: map[string]*schema.Schema{
 			"region": {
 				Type:     schema.TypeString,
@@ -319,6 +327,8 @@ func resourceDatabaseInstance() *schema.Resource {
 					},
 				},
 			},
+
+			"deletion_protection": deletionProtectionSchema(),
 		},
 	}
 }
@@ -429,7 +439,7 @@ func resourceDatabaseInstanceCreate(d *schema.ResourceData, meta interface{}) er
 		checkCapabilities = nil
 	}
 
-	log.Printf("[DEBUG] mcs_db_instance create options: %#v", createOpts)
+	log.Printf("[DEBUG] Creating mcs_db_instance")
 
 	inst := dbInstance{}
 	inst.Instance = createOpts
@@ -500,7 +510,7 @@ func resourceDatabaseInstanceRead(d *schema.ResourceData, meta interface{}) erro
 		return checkDeleted(d, err, "Error retrieving mcs_db_instance")
 	}
 
-	log.Printf("[DEBUG] Retrieved mcs_db_instance %s: %#v", d.Id(), instance)
+	log.Printf("[DEBUG] Retrieved mcs_db_instance %s", d.Id())
 
 	d.Set("name", instance.Name)
 	d.Set("flavor_id", instance.Flavor.ID)
@@ -786,7 +796,17 @@ func resourceDatabaseInstanceUpdate(d *schema.ResourceData, meta interface{}) er
 	return resourceDatabaseInstanceRead(d, meta)
 }
 
+// resourceDatabaseInstanceCustomizeDiff prevents replacing the instance with
+// deletion protection enabled.
+func resourceDatabaseInstanceCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	return customizeDiffDeletionProtection(d, resourceDatabaseInstance().Schema, "mcs_db_instance")
+}
+
 func resourceDatabaseInstanceDelete(d *schema.ResourceData, meta interface{}) error {
+	if err := checkDeletionProtection(d, "mcs_db_instance"); err != nil {
+		return err
+	}
+
 	config := meta.(configer)
 	DatabaseV1Client, err := config.DatabaseV1Client(getRegion(d, config))
 	if err != nil {
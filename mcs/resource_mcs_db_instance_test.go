@@ -2,6 +2,7 @@ package mcs
 
 import (
 	"fmt"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
@@ -107,6 +108,43 @@ func TestAccDatabaseInstance_wal_no_update(t *testing.T) {
 	})
 }
 
+func TestAccDatabaseInstance_deletionProtection(t *testing.T) {
+	var instance instanceResp
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheckDatabase(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDatabaseInstanceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDatabaseInstanceDeletionProtection(osDBDatastoreVersion, true),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckDatabaseInstanceExists(
+						"mcs_db_instance.basic", &instance),
+					resource.TestCheckResourceAttr(
+						"mcs_db_instance.basic", "deletion_protection", "true"),
+				),
+			},
+			{
+				Config:      testAccDatabaseInstanceDeletionProtection(osDBDatastoreVersion+".1", true),
+				ExpectError: regexp.MustCompile("can't be replaced while deletion_protection is enabled"),
+			},
+			{
+				Config:      testAccDatabaseInstanceDeletionProtection(osDBDatastoreVersion, true),
+				Destroy:     true,
+				ExpectError: regexp.MustCompile("can't be deleted while deletion_protection is enabled"),
+			},
+			{
+				Config: testAccDatabaseInstanceDeletionProtection(osDBDatastoreVersion, false),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"mcs_db_instance.basic", "deletion_protection", "false"),
+				),
+			},
+		},
+	})
+}
+
 func testAccCheckDatabaseInstanceExists(n string, instance *instanceResp) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
@@ -301,3 +339,24 @@ resource "mcs_db_instance" "basic" {
 
 }
 `, osFlavorID, osDBDatastoreVersion, osDBDatastoreType, osNetworkID, osKeypairName)
+
+func testAccDatabaseInstanceDeletionProtection(datastoreVersion string, deletionProtection bool) string {
+	return fmt.Sprintf(`
+resource "mcs_db_instance" "basic" {
+  name = "basic"
+  flavor_id = "%s"
+  size = 8
+  volume_type = "ms1"
+
+  datastore {
+    version = "%s"
+    type    = "%s"
+  }
+
+  network {
+    uuid = "%s"
+  }
+  deletion_protection = %t
+}
+`, osFlavorID, datastoreVersion, osDBDatastoreType, osNetworkID, deletionProtection)
+}
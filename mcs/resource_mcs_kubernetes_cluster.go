@@ -3,23 +3,32 @@ package mcs
 import (
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gophercloud/gophercloud"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 
 	"github.com/MailRuCloudSolutions/terraform-provider-mcs/mcs/internal/valid"
 )
 
 const (
-	operationCreate          = 60
-	operationUpdate          = 60
-	operationDelete          = 30
-	createUpdateDelay        = 1
-	createUpdatePollInterval = 20
-	deleteDelay              = 30
-	nodeGroupDeleteDelay     = 10
-	deletePollInterval       = 10
+	operationCreate = 60
+	operationUpdate = 60
+	operationDelete = 30
+)
+
+// Kubernetes state change intervals, shortened by tests run against the MCS
+// API emulator.
+var (
+	createUpdateDelay        = 1 * time.Minute
+	createUpdatePollInterval = 20 * time.Second
+	deleteDelay              = 30 * time.Second
+	nodeGroupDeleteDelay     = 10 * time.Second
+	deletePollInterval       = 10 * time.Second
 )
 
 type clusterStatus string
@@ -34,6 +43,9 @@ var (
 	clusterStatusShutoff      clusterStatus = "SHUTOFF"
 )
 
+// clusterMasterCounts are the allowed numbers of master nodes.
+var clusterMasterCounts = []int{1, 3, 5}
+
 var stateStatusMap = map[clusterStatus]string{
 	clusterStatusRunning: "turn_on_cluster",
 	clusterStatusShutoff: "turn_off_cluster",
@@ -46,7 +58,10 @@ func resourceKubernetesCluster() *schema.Resource {
 		Update: resourceKubernetesClusterUpdate,
 		Delete: resourceKubernetesClusterDelete,
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			State: func(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+				d.Set("deletion_protection", false)
+				return []*schema.ResourceData{d}, nil
+			},
 		},
 
 		Timeouts: &schema.ResourceTimeout{
@@ -55,6 +70,8 @@ func resourceKubernetesCluster() *schema.Resource {
 			Delete: schema.DefaultTimeout(operationDelete * time.Minute),
 		},
 
+		CustomizeDiff: resourceKubernetesClusterCustomizeDiff,
+
 		Schema: map[string]*schema.Schema{
 			"region": {
 				Type:     schema.TypeString,
@@ -119,15 +136,14 @@ func resourceKubernetesCluster() *schema.Resource {
 				Type:     schema.TypeMap,
 				Optional: true,
 				Computed: true,
-				ForceNew: true,
 				Elem:     &schema.Schema{Type: schema.TypeString},
 				Set:      schema.HashString,
 			},
 			"master_count": {
-				Type:     schema.TypeInt,
-				Optional: true,
-				ForceNew: true,
-				Computed: true,
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.IntInSlice(clusterMasterCounts),
 			},
 			"master_addresses": {
 				Type:     schema.TypeList,
@@ -208,6 +224,125 @@ func resourceKubernetesCluster() *schema.Resource {
 				Computed: true,
 				Elem:     &schema.Schema{Type: schema.TypeString},
 			},
+			"node_groups": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"node_count": {
+							Type:     schema.TypeInt,
+							Optional: true,
+							Default:  1,
+							DiffSuppressFunc: func(k, old, new string, d *schema.ResourceData) bool {
+								// Suppress diff if node_count is managed by autoscaler
+								autoscaling := strings.TrimSuffix(k, "node_count") + "autoscaling_enabled"
+								return d.Get(autoscaling).(bool) && old != ""
+							},
+						},
+						"max_nodes": {
+							Type:     schema.TypeInt,
+							Optional: true,
+							Computed: true,
+						},
+						"min_nodes": {
+							Type:     schema.TypeInt,
+							Optional: true,
+							Computed: true,
+						},
+						"volume_size": {
+							Type:     schema.TypeInt,
+							Optional: true,
+							Computed: true,
+						},
+						"volume_type": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+						},
+						"flavor_id": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+						},
+						"autoscaling_enabled": {
+							Type:     schema.TypeBool,
+							Optional: true,
+						},
+						"availability_zones": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+			"upgrade_policy": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"rolling_enabled": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  true,
+						},
+						"max_surge": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							ValidateFunc: validation.IntAtLeast(0),
+						},
+						"max_unavailable": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							ValidateFunc: validation.IntAtLeast(0),
+						},
+						"drain_timeout": {
+							Type:     schema.TypeString,
+							Optional: true,
+							ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+								if _, err := time.ParseDuration(val.(string)); err != nil {
+									errs = append(errs, fmt.Errorf("%q must be a duration like 10m: %s", key, err))
+								}
+								return
+							},
+						},
+					},
+				},
+			},
+			"deletion_protection": deletionProtectionSchema(),
+			"k8s_config": {
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+			"host": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"cluster_ca_certificate": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"client_certificate": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"client_key": {
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+			"token": {
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
 		},
 	}
 }
@@ -244,11 +379,7 @@ func resourceKubernetesClusterCreate(d *schema.ResourceData, meta interface{}) e
 	}
 
 	if masterCount, ok := d.GetOk("master_count"); ok {
-		mCount := masterCount.(int)
-		if mCount < 1 {
-			return fmt.Errorf("master_count if set must be greater or equal 1: %s", err)
-		}
-		createOpts.MasterCount = mCount
+		createOpts.MasterCount = masterCount.(int)
 	}
 
 	if registriesRaw, ok := d.GetOk("insecure_registries"); ok {
@@ -273,8 +404,8 @@ func resourceKubernetesClusterCreate(d *schema.ResourceData, meta interface{}) e
 		Target:       []string{string(clusterStatusRunning)},
 		Refresh:      kubernetesStateRefreshFunc(containerInfraClient, s),
 		Timeout:      d.Timeout(schema.TimeoutCreate),
-		Delay:        createUpdateDelay * time.Minute,
-		PollInterval: createUpdatePollInterval * time.Second,
+		Delay:        createUpdateDelay,
+		PollInterval: createUpdatePollInterval,
 	}
 	_, err = stateConf.WaitForState()
 	if err != nil {
@@ -282,10 +413,49 @@ func resourceKubernetesClusterCreate(d *schema.ResourceData, meta interface{}) e
 			"error waiting for mcs_kubernetes_cluster %s to become ready: %s", s, err)
 	}
 
+	if rawNodeGroups, ok := d.GetOk("node_groups"); ok {
+		err := addClusterNodeGroups(d, containerInfraClient, rawNodeGroups.([]interface{}))
+		if err != nil {
+			return err
+		}
+	}
+
 	log.Printf("[DEBUG] Created mcs_kubernetes_cluster %s", s)
 	return resourceKubernetesClusterRead(d, meta)
 }
 
+// addClusterNodeGroups creates the inline node groups of the new cluster.
+func addClusterNodeGroups(d *schema.ResourceData, containerInfraClient ContainerClient, rawNodeGroups []interface{}) error {
+	nodeGroups, err := extractKubernetesGroupMap(rawNodeGroups)
+	if err != nil {
+		return fmt.Errorf("error parsing node_groups of mcs_kubernetes_cluster: %s", err)
+	}
+
+	addOpts := nodeGroupBatchAddParams{
+		Action:  "batch_add_ng",
+		Payload: nodeGroups,
+	}
+	_, err = clusterBatchAddNodeGroups(containerInfraClient, d.Id(), &addOpts).Extract()
+	if err != nil {
+		return fmt.Errorf("error adding node groups to mcs_kubernetes_cluster %s: %s", d.Id(), err)
+	}
+
+	stateConf := &resource.StateChangeConf{
+		Pending:      []string{string(clusterStatusReconciling)},
+		Target:       []string{string(clusterStatusRunning)},
+		Refresh:      kubernetesStateRefreshFunc(containerInfraClient, d.Id()),
+		Timeout:      d.Timeout(schema.TimeoutCreate),
+		Delay:        createUpdateDelay,
+		PollInterval: createUpdatePollInterval,
+	}
+	_, err = stateConf.WaitForState()
+	if err != nil {
+		return fmt.Errorf(
+			"error waiting for node groups of mcs_kubernetes_cluster %s to become ready: %s", d.Id(), err)
+	}
+	return nil
+}
+
 func resourceKubernetesClusterRead(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(configer)
 	containerInfraClient, err := config.ContainerInfraV1Client(getRegion(d, config))
@@ -298,19 +468,36 @@ func resourceKubernetesClusterRead(d *schema.ResourceData, meta interface{}) err
 		return checkDeleted(d, err, "error retrieving mcs_kubernetes_cluster")
 	}
 
-	log.Printf("[DEBUG] retrieved mcs_kubernetes_cluster %s: %#v", d.Id(), cluster)
+	log.Printf("[DEBUG] retrieved mcs_kubernetes_cluster %s", d.Id())
 
-	// Get and check labels map.
-	rawLabels := d.Get("labels").(map[string]interface{})
-	labels, err := extractKubernetesLabelsMap(rawLabels)
+	// Labels set by the service or inherited from the cluster template are
+	// not managed by the provider.
+	templateLabels, err := clusterTemplateLabels(containerInfraClient, cluster.ClusterTemplateID)
 	if err != nil {
-		return err
+		return fmt.Errorf("error retrieving labels of mcs_kubernetes_cluster %s: %s", d.Id(), err)
 	}
-
+	labels := filterClusterLabels(cluster.Labels, templateLabels, d.Get("labels").(map[string]interface{}))
 	if err := d.Set("labels", labels); err != nil {
 		return fmt.Errorf("unable to set mcs_kubernetes_cluster labels: %s", err)
 	}
 
+	// Only the inline node groups declared in the configuration are read,
+	// node groups added by mcs_kubernetes_node_group resources are not
+	// managed by the cluster.
+	if rawNodeGroups, ok := d.GetOk("node_groups"); ok {
+		var names []string
+		for _, ng := range rawNodeGroups.([]interface{}) {
+			names = append(names, ng.(map[string]interface{})["name"].(string))
+		}
+		nodeGroups, err := getClusterNodeGroups(containerInfraClient, d.Id(), names)
+		if err != nil {
+			return fmt.Errorf("error retrieving node groups of mcs_kubernetes_cluster %s: %s", d.Id(), err)
+		}
+		if err := d.Set("node_groups", flattenClusterNodeGroups(nodeGroups)); err != nil {
+			return fmt.Errorf("unable to set mcs_kubernetes_cluster node_groups: %s", err)
+		}
+	}
+
 	d.Set("name", cluster.Name)
 	d.Set("api_address", cluster.APIAddress)
 	d.Set("cluster_template_id", cluster.ClusterTemplateID)
@@ -344,6 +531,12 @@ func resourceKubernetesClusterRead(d *schema.ResourceData, meta interface{}) err
 		d.Set("subnet_id", cluster.Labels["fixed_subnet"])
 	}
 
+	// The kubeconfig is not available while the cluster is being created
+	// or is turned off, so the last known one is kept.
+	if err := setKubeConfig(d, containerInfraClient); err != nil {
+		log.Printf("[DEBUG] Unable to set mcs_kubernetes_cluster %s kubeconfig: %s", d.Id(), err)
+	}
+
 	if err := d.Set("created_at", getTimestamp(&cluster.CreatedAt)); err != nil {
 		log.Printf("[DEBUG] Unable to set mcs_kubernetes_cluster created_at: %s", err)
 	}
@@ -354,6 +547,99 @@ func resourceKubernetesClusterRead(d *schema.ResourceData, meta interface{}) err
 	return nil
 }
 
+// getClusterNodeGroups gets the cluster node groups with the names in the
+// same order. The node groups which are not found are skipped.
+func getClusterNodeGroups(client ContainerClient, clusterID string, names []string) ([]*nodeGroup, error) {
+	ids, err := clusterNodeGroupIDs(client, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodeGroups []*nodeGroup
+	for _, name := range names {
+		id, ok := ids[name]
+		if !ok {
+			log.Printf("[DEBUG] Node group %s of mcs_kubernetes_cluster %s is not found", name, clusterID)
+			continue
+		}
+		// The list has only a summary of the node groups.
+		ng, err := nodeGroupGet(client, id).Extract()
+		if err != nil {
+			return nil, err
+		}
+		nodeGroups = append(nodeGroups, ng)
+	}
+	return nodeGroups, nil
+}
+
+// clusterNodeGroupIDs returns the UUIDs of the cluster node groups by their names.
+func clusterNodeGroupIDs(client ContainerClient, clusterID string) (map[string]string, error) {
+	list, err := clusterNodeGroupList(client, clusterID).Extract()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]string, len(list))
+	for _, ng := range list {
+		ids[ng.Name] = ng.UUID
+	}
+	return ids, nil
+}
+
+func flattenClusterNodeGroups(nodeGroups []*nodeGroup) []map[string]interface{} {
+	flattened := make([]map[string]interface{}, len(nodeGroups))
+	for i, ng := range nodeGroups {
+		flattened[i] = map[string]interface{}{
+			"name":                ng.Name,
+			"node_count":          ng.NodeCount,
+			"max_nodes":           ng.MaxNodes,
+			"min_nodes":           ng.MinNodes,
+			"volume_size":         ng.VolumeSize,
+			"volume_type":         ng.VolumeType,
+			"flavor_id":           ng.FlavorID,
+			"autoscaling_enabled": ng.Autoscaling,
+			"availability_zones":  ng.AvailabilityZones,
+		}
+	}
+	return flattened
+}
+
+// clusterTemplateLabels returns the labels of the cluster template, or nil if
+// the template is already deleted.
+func clusterTemplateLabels(client ContainerClient, id string) (map[string]string, error) {
+	template, err := clusterTemplateGet(client, id).Extract()
+	if err != nil {
+		if _, ok := err.(gophercloud.ErrDefault404); ok {
+			log.Printf("[DEBUG] Cluster template %s is not found, its labels are unknown", id)
+			return nil, nil
+		}
+		return nil, err
+	}
+	if template.Labels == nil {
+		return map[string]string{}, nil
+	}
+	return template.Labels, nil
+}
+
+// setKubeConfig sets the kubeconfig of the cluster and credentials parsed from it.
+func setKubeConfig(d *schema.ResourceData, client ContainerClient) error {
+	k8sConfig, err := k8sConfigGet(client, d.Id())
+	if err != nil {
+		return err
+	}
+	creds, err := parseKubeConfig(k8sConfig)
+	if err != nil {
+		return err
+	}
+
+	d.Set("k8s_config", k8sConfig)
+	d.Set("host", creds.Host)
+	d.Set("cluster_ca_certificate", creds.ClusterCACertificate)
+	d.Set("client_certificate", creds.ClientCertificate)
+	d.Set("client_key", creds.ClientKey)
+	d.Set("token", creds.Token)
+	return nil
+}
+
 func resourceKubernetesClusterUpdate(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(configer)
 	containerInfraClient, err := config.ContainerInfraV1Client(getRegion(d, config))
@@ -364,8 +650,8 @@ func resourceKubernetesClusterUpdate(d *schema.ResourceData, meta interface{}) e
 	stateConf := &resource.StateChangeConf{
 		Refresh:      kubernetesStateRefreshFunc(containerInfraClient, d.Id()),
 		Timeout:      d.Timeout(schema.TimeoutUpdate),
-		Delay:        createUpdateDelay * time.Minute,
-		PollInterval: createUpdatePollInterval * time.Second,
+		Delay:        createUpdateDelay,
+		PollInterval: createUpdatePollInterval,
 		Pending:      []string{string(clusterStatusReconciling)},
 		Target:       []string{string(clusterStatusRunning)},
 	}
@@ -390,6 +676,18 @@ func resourceKubernetesClusterUpdate(d *schema.ResourceData, meta interface{}) e
 			if err != nil {
 				return err
 			}
+			err = checkForMasterCount(d, containerInfraClient, stateConf)
+			if err != nil {
+				return err
+			}
+			err = checkForLabels(d, containerInfraClient, stateConf)
+			if err != nil {
+				return err
+			}
+			err = checkForNodeGroups(d, containerInfraClient, stateConf)
+			if err != nil {
+				return err
+			}
 		} else {
 			return fmt.Errorf("changing cluster attributes is prohibited when cluster has SHUTOFF status")
 		}
@@ -402,6 +700,18 @@ func resourceKubernetesClusterUpdate(d *schema.ResourceData, meta interface{}) e
 		if err != nil {
 			return err
 		}
+		err = checkForMasterCount(d, containerInfraClient, stateConf)
+		if err != nil {
+			return err
+		}
+		err = checkForLabels(d, containerInfraClient, stateConf)
+		if err != nil {
+			return err
+		}
+		err = checkForNodeGroups(d, containerInfraClient, stateConf)
+		if err != nil {
+			return err
+		}
 		_, err = checkForStatus(d, containerInfraClient, cluster)
 		if err != nil {
 			return err
@@ -415,12 +725,10 @@ func resourceKubernetesClusterUpdate(d *schema.ResourceData, meta interface{}) e
 
 func checkForClusterTemplateID(d *schema.ResourceData, containerInfraClient ContainerClient, stateConf *resource.StateChangeConf) error {
 	if d.HasChange("cluster_template_id") {
-		upgradeOpts := clusterUpgradeOpts{
-			ClusterTemplateID: d.Get("cluster_template_id").(string),
-			RollingEnabled:    true,
-		}
+		upgradeOpts := expandClusterUpgradePolicy(d.Get("upgrade_policy").([]interface{}))
+		upgradeOpts.ClusterTemplateID = d.Get("cluster_template_id").(string)
 
-		_, err := clusterUpgrade(containerInfraClient, d.Id(), &upgradeOpts).Extract()
+		_, err := clusterUpgrade(containerInfraClient, d.Id(), upgradeOpts).Extract()
 		if err != nil {
 			return fmt.Errorf("error upgrade cluster : %s", err)
 		}
@@ -434,6 +742,24 @@ func checkForClusterTemplateID(d *schema.ResourceData, containerInfraClient Cont
 	return nil
 }
 
+// expandClusterUpgradePolicy builds upgrade options from the upgrade_policy
+// block. Upgrades are rolling by default.
+func expandClusterUpgradePolicy(v []interface{}) *clusterUpgradeOpts {
+	opts := &clusterUpgradeOpts{RollingEnabled: true}
+	if len(v) == 0 || v[0] == nil {
+		return opts
+	}
+	policy := v[0].(map[string]interface{})
+	opts.RollingEnabled = policy["rolling_enabled"].(bool)
+	opts.MaxSurge = policy["max_surge"].(int)
+	opts.MaxUnavailable = policy["max_unavailable"].(int)
+	// The duration is validated by the schema.
+	if drainTimeout, _ := time.ParseDuration(policy["drain_timeout"].(string)); drainTimeout > 0 {
+		opts.DrainTimeout = int(drainTimeout.Seconds())
+	}
+	return opts
+}
+
 func checkForMasterFlavor(d *schema.ResourceData, containerInfraClient ContainerClient, stateConf *resource.StateChangeConf) error {
 	if d.HasChange("master_flavor") {
 		upgradeOpts := clusterActionsBaseOpts{
@@ -457,13 +783,275 @@ func checkForMasterFlavor(d *schema.ResourceData, containerInfraClient Container
 	return nil
 }
 
+func checkForMasterCount(d *schema.ResourceData, containerInfraClient ContainerClient, stateConf *resource.StateChangeConf) error {
+	if d.HasChange("master_count") {
+		scaleOpts := clusterActionsBaseOpts{
+			Action: "scale_masters",
+			Payload: map[string]int{
+				"master_count": d.Get("master_count").(int),
+			},
+		}
+
+		_, err := clusterUpdateMasters(containerInfraClient, d.Id(), &scaleOpts).Extract()
+		if err != nil {
+			return fmt.Errorf("error scaling cluster's masters : %s", err)
+		}
+
+		_, err = stateConf.WaitForState()
+		if err != nil {
+			return fmt.Errorf(
+				"error waiting for mcs_kubernetes_cluster %s to become scaled: %s", d.Id(), err)
+		}
+	}
+	return nil
+}
+
+func checkForLabels(d *schema.ResourceData, containerInfraClient ContainerClient, stateConf *resource.StateChangeConf) error {
+	if d.HasChange("labels") {
+		// The labels are replaced as a whole, so the ones set by the service
+		// are taken from the cluster as it is after the preceding updates.
+		cluster, err := clusterGet(containerInfraClient, d.Id()).Extract()
+		if err != nil {
+			return fmt.Errorf("error retrieving cluster: %s", err)
+		}
+		oldLabels, newLabels := d.GetChange("labels")
+		labels, err := mergeClusterLabels(cluster.Labels, oldLabels.(map[string]interface{}), newLabels.(map[string]interface{}))
+		if err != nil {
+			return err
+		}
+		patchOpts := nodeGroupClusterPatchOpts{
+			nodeGroupPatchParams{
+				Op:    "replace",
+				Path:  "/labels",
+				Value: labels,
+			},
+		}
+
+		_, err = clusterPatch(containerInfraClient, d.Id(), &patchOpts).Extract()
+		if err != nil {
+			return fmt.Errorf("error updating cluster's labels : %s", err)
+		}
+
+		_, err = stateConf.WaitForState()
+		if err != nil {
+			return fmt.Errorf(
+				"error waiting for mcs_kubernetes_cluster %s to become updated: %s", d.Id(), err)
+		}
+	}
+	return nil
+}
+
+// checkForNodeGroups applies the changes of the inline node groups, which are
+// matched by their names. The removed node groups are deleted, the new ones
+// are added and the remaining ones are scaled and patched. The node groups
+// with changed attributes which can't be updated in place are replaced.
+func checkForNodeGroups(d *schema.ResourceData, containerInfraClient ContainerClient, stateConf *resource.StateChangeConf) error {
+	if !d.HasChange("node_groups") {
+		return nil
+	}
+	o, n := d.GetChange("node_groups")
+	removed, added, updated := diffClusterNodeGroups(o.([]interface{}), n.([]interface{}))
+
+	ids, err := clusterNodeGroupIDs(containerInfraClient, d.Id())
+	if err != nil {
+		return fmt.Errorf("error retrieving node groups of mcs_kubernetes_cluster %s: %s", d.Id(), err)
+	}
+
+	for _, name := range removed {
+		id, ok := ids[name]
+		if !ok {
+			continue
+		}
+		log.Printf("[DEBUG] Deleting node group %s of mcs_kubernetes_cluster %s", name, d.Id())
+		if err := nodeGroupDelete(containerInfraClient, id).ExtractErr(); err != nil {
+			if _, ok := err.(gophercloud.ErrDefault404); !ok {
+				return fmt.Errorf("error deleting node group %s of mcs_kubernetes_cluster %s: %s", name, d.Id(), err)
+			}
+		}
+		deleteConf := &resource.StateChangeConf{
+			Pending:      []string{string(clusterStatusReconciling)},
+			Target:       []string{string(clusterStatusRunning)},
+			Refresh:      nodeGroupStateRefreshFunc(containerInfraClient, d.Id(), id),
+			Timeout:      d.Timeout(schema.TimeoutUpdate),
+			Delay:        nodeGroupDeleteDelay,
+			PollInterval: deletePollInterval,
+		}
+		if _, err := deleteConf.WaitForState(); err != nil {
+			return fmt.Errorf(
+				"error waiting for node group %s of mcs_kubernetes_cluster %s to become deleted: %s", name, d.Id(), err)
+		}
+	}
+
+	if len(added) > 0 {
+		if err := addClusterNodeGroups(d, containerInfraClient, added); err != nil {
+			return err
+		}
+	}
+
+	for _, ng := range updated {
+		name := ng.new["name"].(string)
+		id, ok := ids[name]
+		if !ok {
+			return fmt.Errorf("node group %s of mcs_kubernetes_cluster %s is not found", name, d.Id())
+		}
+
+		// The node count is managed by the autoscaler when it is enabled.
+		if ng.old["node_count"] != ng.new["node_count"] && !ng.new["autoscaling_enabled"].(bool) {
+			s, err := nodeGroupGet(containerInfraClient, id).Extract()
+			if err != nil {
+				return fmt.Errorf("error retrieving node group %s of mcs_kubernetes_cluster %s: %s", name, d.Id(), err)
+			}
+			scaleOpts := nodeGroupScaleOpts{
+				Delta: ng.new["node_count"].(int) - s.NodeCount,
+			}
+			if scaleOpts.Delta != 0 {
+				if _, err := nodeGroupScale(containerInfraClient, id, &scaleOpts).Extract(); err != nil {
+					return fmt.Errorf("error scaling node group %s of mcs_kubernetes_cluster %s: %s", name, d.Id(), err)
+				}
+				if _, err := stateConf.WaitForState(); err != nil {
+					return fmt.Errorf(
+						"error waiting for node group %s of mcs_kubernetes_cluster %s to become scaled: %s", name, d.Id(), err)
+				}
+			}
+		}
+
+		var patchOpts nodeGroupClusterPatchOpts
+		for _, key := range []string{"max_nodes", "min_nodes"} {
+			if ng.old[key] != ng.new[key] {
+				patchOpts = append(patchOpts, nodeGroupPatchParams{
+					Path:  "/" + key,
+					Value: ng.new[key].(int),
+					Op:    "replace",
+				})
+			}
+		}
+		if ng.old["autoscaling_enabled"] != ng.new["autoscaling_enabled"] {
+			patchOpts = append(patchOpts, nodeGroupPatchParams{
+				Path:  "/autoscaling_enabled",
+				Value: strconv.FormatBool(ng.new["autoscaling_enabled"].(bool)),
+				Op:    "replace",
+			})
+		}
+		if len(patchOpts) > 0 {
+			if _, err := nodeGroupPatch(containerInfraClient, id, &patchOpts).Extract(); err != nil {
+				return fmt.Errorf("error updating node group %s of mcs_kubernetes_cluster %s: %s", name, d.Id(), err)
+			}
+			if _, err := stateConf.WaitForState(); err != nil {
+				return fmt.Errorf(
+					"error waiting for node group %s of mcs_kubernetes_cluster %s to become updated: %s", name, d.Id(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// resourceKubernetesClusterCustomizeDiff rejects arguments the container-infra
+// API microversion doesn't support, changes of labels which can't be updated
+// in place and reducing the number of masters, prevents replacing the cluster
+// with deletion protection enabled and validates the upgrade to a new cluster
+// template.
+func resourceKubernetesClusterCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if err := checkClusterMicroVersion(d, meta.(configer).ContainerInfraV1MicroVersion); err != nil {
+		return err
+	}
+
+	if v := d.Get("upgrade_policy").([]interface{}); len(v) > 0 && v[0] != nil {
+		policy := v[0].(map[string]interface{})
+		if !policy["rolling_enabled"].(bool) && (policy["max_surge"].(int) > 0 || policy["max_unavailable"].(int) > 0) {
+			return fmt.Errorf("upgrade_policy max_surge and max_unavailable require rolling_enabled")
+		}
+	}
+
+	if d.Id() == "" {
+		return nil
+	}
+
+	// Forcing a new cluster would mark all the changed labels, so the
+	// immutable ones are named in the error instead.
+	if d.HasChange("labels") {
+		oldLabels, newLabels := d.GetChange("labels")
+		changed := changedImmutableLabels(oldLabels.(map[string]interface{}), newLabels.(map[string]interface{}))
+		if len(changed) > 0 {
+			return fmt.Errorf("labels %s of mcs_kubernetes_cluster %s can't be updated in place, "+
+				"taint the cluster to recreate it with the new labels", strings.Join(changed, ", "), d.Id())
+		}
+	}
+
+	// Masters can only be added to a running cluster.
+	if d.HasChange("master_count") {
+		oldCount, newCount := d.GetChange("master_count")
+		if newCount.(int) < oldCount.(int) {
+			return fmt.Errorf("master_count can't be decreased from %d to %d", oldCount, newCount)
+		}
+	}
+
+	err := customizeDiffDeletionProtection(d, resourceKubernetesCluster().Schema, "mcs_kubernetes_cluster")
+	if err != nil {
+		return err
+	}
+
+	// A recreated cluster is not upgraded, so any template can be used.
+	recreated := forceNewChanges(d, resourceKubernetesCluster().Schema, "")
+	if d.HasChange("cluster_template_id") && d.NewValueKnown("cluster_template_id") && len(recreated) == 0 {
+		if err := validateClusterTemplateChange(d, meta); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clusterDiffClient returns container infra client of the cluster region.
+func clusterDiffClient(d *schema.ResourceDiff, meta interface{}) (ContainerClient, error) {
+	config := meta.(configer)
+	region := d.Get("region").(string)
+	if region == "" {
+		region = config.GetRegion()
+	}
+	containerInfraClient, err := config.ContainerInfraV1Client(region)
+	if err != nil {
+		return nil, fmt.Errorf("error creating container infra client: %s", err)
+	}
+	return containerInfraClient, nil
+}
+
+// validateClusterTemplateChange checks at plan time that the cluster can be
+// upgraded to the new cluster template. The old cluster template may be
+// already deleted, then the upgrade is left to the API to validate.
+func validateClusterTemplateChange(d *schema.ResourceDiff, meta interface{}) error {
+	containerInfraClient, err := clusterDiffClient(d, meta)
+	if err != nil {
+		return err
+	}
+
+	oldID, newID := d.GetChange("cluster_template_id")
+	from, err := clusterTemplateGet(containerInfraClient, oldID.(string)).Extract()
+	if _, ok := err.(gophercloud.ErrDefault404); ok {
+		log.Printf("[WARN] mcs_kubernetes_clustertemplate %s is not found, skipping upgrade validation of mcs_kubernetes_cluster %s",
+			oldID, d.Id())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting mcs_kubernetes_clustertemplate %s: %s", oldID, err)
+	}
+	to, err := clusterTemplateGet(containerInfraClient, newID.(string)).Extract()
+	if err != nil {
+		return fmt.Errorf("error getting mcs_kubernetes_clustertemplate %s: %s", newID, err)
+	}
+
+	if err := validateClusterUpgrade(from, to, time.Now()); err != nil {
+		return fmt.Errorf("unable to upgrade mcs_kubernetes_cluster %s: %s", d.Id(), err)
+	}
+	return nil
+}
+
 func checkForStatus(d *schema.ResourceData, containerInfraClient ContainerClient, cluster *cluster) (bool, error) {
 
 	turnOffConf := &resource.StateChangeConf{
 		Refresh:      kubernetesStateRefreshFunc(containerInfraClient, d.Id()),
 		Timeout:      d.Timeout(schema.TimeoutUpdate),
-		Delay:        createUpdateDelay * time.Minute,
-		PollInterval: createUpdatePollInterval * time.Second,
+		Delay:        createUpdateDelay,
+		PollInterval: createUpdatePollInterval,
 		Pending:      []string{string(clusterStatusRunning)},
 		Target:       []string{string(clusterStatusShutoff)},
 	}
@@ -471,8 +1059,8 @@ func checkForStatus(d *schema.ResourceData, containerInfraClient ContainerClient
 	turnOnConf := &resource.StateChangeConf{
 		Refresh:      kubernetesStateRefreshFunc(containerInfraClient, d.Id()),
 		Timeout:      d.Timeout(schema.TimeoutUpdate),
-		Delay:        createUpdateDelay * time.Minute,
-		PollInterval: createUpdatePollInterval * time.Second,
+		Delay:        createUpdateDelay,
+		PollInterval: createUpdatePollInterval,
 		Pending:      []string{string(clusterStatusShutoff)},
 		Target:       []string{string(clusterStatusRunning)},
 	}
@@ -512,6 +1100,10 @@ func checkForStatus(d *schema.ResourceData, containerInfraClient ContainerClient
 }
 
 func resourceKubernetesClusterDelete(d *schema.ResourceData, meta interface{}) error {
+	if err := checkDeletionProtection(d, "mcs_kubernetes_cluster"); err != nil {
+		return err
+	}
+
 	config := meta.(configer)
 	client, err := config.ContainerInfraV1Client(getRegion(d, config))
 	if err != nil {
@@ -527,8 +1119,8 @@ func resourceKubernetesClusterDelete(d *schema.ResourceData, meta interface{}) e
 		Target:       []string{string(clusterStatusDeleted)},
 		Refresh:      kubernetesStateRefreshFunc(client, d.Id()),
 		Timeout:      d.Timeout(schema.TimeoutDelete),
-		Delay:        deleteDelay * time.Second,
-		PollInterval: deletePollInterval * time.Second,
+		Delay:        deleteDelay,
+		PollInterval: deletePollInterval,
 	}
 	if _, err = stateConf.WaitForState(); err != nil {
 		return fmt.Errorf(
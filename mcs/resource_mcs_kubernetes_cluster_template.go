@@ -0,0 +1,378 @@
+package mcs
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+// clusterTemplatePatchPaths maps updatable attributes of the cluster template
+// to their paths in the container-infra API.
+var clusterTemplatePatchPaths = map[string]string{
+	"name":                  "/name",
+	"image":                 "/image_id",
+	"apiserver_port":        "/apiserver_port",
+	"dns_nameserver":        "/dns_nameserver",
+	"docker_storage_driver": "/docker_storage_driver",
+	"docker_volume_size":    "/docker_volume_size",
+	"external_network_id":   "/external_network_id",
+	"flavor":                "/flavor_id",
+	"master_flavor":         "/master_flavor_id",
+	"floating_ip_enabled":   "/floating_ip_enabled",
+	"insecure_registry":     "/insecure_registry",
+	"keypair_id":            "/keypair_id",
+	"labels":                "/labels",
+	"master_lb_enabled":     "/master_lb_enabled",
+	"network_driver":        "/network_driver",
+	"no_proxy":              "/no_proxy",
+	"public":                "/public",
+	"registry_enabled":      "/registry_enabled",
+	"server_type":           "/server_type",
+	"tls_disabled":          "/tls_disabled",
+	"volume_driver":         "/volume_driver",
+}
+
+func resourceKubernetesClusterTemplate() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceKubernetesClusterTemplateCreate,
+		Read:   resourceKubernetesClusterTemplateRead,
+		Update: resourceKubernetesClusterTemplateUpdate,
+		Delete: resourceKubernetesClusterTemplateDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"region": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+				Computed: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"coe": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+				Default:  "kubernetes",
+			},
+			"image": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"apiserver_port": {
+				Type:     schema.TypeInt,
+				Optional: true,
+				Computed: true,
+			},
+			"dns_nameserver": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"docker_storage_driver": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"docker_volume_size": {
+				Type:     schema.TypeInt,
+				Optional: true,
+				Computed: true,
+			},
+			"external_network_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"flavor": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"master_flavor": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"floating_ip_enabled": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"insecure_registry": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"keypair_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"labels": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"master_lb_enabled": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"network_driver": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"no_proxy": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"public": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"registry_enabled": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"server_type": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"tls_disabled": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"volume_driver": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"cluster_distro": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"version": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"project_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"user_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"created_at": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"updated_at": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"deprecated_at": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceKubernetesClusterTemplateCreate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(configer)
+	containerInfraClient, err := config.ContainerInfraV1Client(getRegion(d, config))
+	if err != nil {
+		return fmt.Errorf("error creating container infra client: %s", err)
+	}
+
+	labels, err := extractKubernetesLabelsMap(d.Get("labels").(map[string]interface{}))
+	if err != nil {
+		return err
+	}
+
+	createOpts := clusterTemplateCreateOpts{
+		Name:                d.Get("name").(string),
+		COE:                 d.Get("coe").(string),
+		ImageID:             d.Get("image").(string),
+		APIServerPort:       d.Get("apiserver_port").(int),
+		DNSNameServer:       d.Get("dns_nameserver").(string),
+		DockerStorageDriver: d.Get("docker_storage_driver").(string),
+		DockerVolumeSize:    d.Get("docker_volume_size").(int),
+		ExternalNetworkID:   d.Get("external_network_id").(string),
+		FlavorID:            d.Get("flavor").(string),
+		MasterFlavorID:      d.Get("master_flavor").(string),
+		InsecureRegistry:    d.Get("insecure_registry").(string),
+		KeyPairID:           d.Get("keypair_id").(string),
+		Labels:              labels,
+		NetworkDriver:       d.Get("network_driver").(string),
+		NoProxy:             d.Get("no_proxy").(string),
+		ServerType:          d.Get("server_type").(string),
+		VolumeDriver:        d.Get("volume_driver").(string),
+	}
+
+	// Booleans are sent only if set, otherwise defaults of the service are used.
+	for key, field := range map[string]**bool{
+		"floating_ip_enabled": &createOpts.FloatingIPEnabled,
+		"master_lb_enabled":   &createOpts.MasterLBEnabled,
+		"public":              &createOpts.Public,
+		"registry_enabled":    &createOpts.RegistryEnabled,
+		"tls_disabled":        &createOpts.TLSDisabled,
+	} {
+		if v, ok := d.GetOkExists(key); ok {
+			value := v.(bool)
+			*field = &value
+		}
+	}
+
+	ct, err := clusterTemplateCreate(containerInfraClient, &createOpts).Extract()
+	if err != nil {
+		return fmt.Errorf("error creating mcs_kubernetes_clustertemplate: %s", err)
+	}
+
+	d.SetId(ct.UUID)
+
+	log.Printf("[DEBUG] Created mcs_kubernetes_clustertemplate %s", ct.UUID)
+	return resourceKubernetesClusterTemplateRead(d, meta)
+}
+
+func resourceKubernetesClusterTemplateRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(configer)
+	containerInfraClient, err := config.ContainerInfraV1Client(getRegion(d, config))
+	if err != nil {
+		return fmt.Errorf("error creating container infra client: %s", err)
+	}
+
+	ct, err := clusterTemplateGet(containerInfraClient, d.Id()).Extract()
+	if err != nil {
+		return checkDeleted(d, err, "error retrieving mcs_kubernetes_clustertemplate")
+	}
+
+	log.Printf("[DEBUG] retrieved mcs_kubernetes_clustertemplate %s", d.Id())
+
+	if err := d.Set("labels", ct.Labels); err != nil {
+		return fmt.Errorf("unable to set mcs_kubernetes_clustertemplate labels: %s", err)
+	}
+
+	d.Set("name", ct.Name)
+	d.Set("coe", ct.COE)
+	d.Set("image", ct.ImageID)
+	d.Set("apiserver_port", ct.APIServerPort)
+	d.Set("cluster_distro", ct.ClusterDistro)
+	d.Set("dns_nameserver", ct.DNSNameServer)
+	d.Set("docker_storage_driver", ct.DockerStorageDriver)
+	d.Set("docker_volume_size", ct.DockerVolumeSize)
+	d.Set("external_network_id", ct.ExternalNetworkID)
+	d.Set("flavor", ct.FlavorID)
+	d.Set("master_flavor", ct.MasterFlavorID)
+	d.Set("floating_ip_enabled", ct.FloatingIPEnabled)
+	d.Set("insecure_registry", ct.InsecureRegistry)
+	d.Set("keypair_id", ct.KeyPairID)
+	d.Set("master_lb_enabled", ct.MasterLBEnabled)
+	d.Set("network_driver", ct.NetworkDriver)
+	d.Set("no_proxy", ct.NoProxy)
+	d.Set("public", ct.Public)
+	d.Set("registry_enabled", ct.RegistryEnabled)
+	d.Set("server_type", ct.ServerType)
+	d.Set("tls_disabled", ct.TLSDisabled)
+	d.Set("volume_driver", ct.VolumeDriver)
+	d.Set("version", ct.Version)
+	d.Set("project_id", ct.ProjectID)
+	d.Set("user_id", ct.UserID)
+	d.Set("region", getRegion(d, config))
+	d.Set("deprecated_at", "")
+
+	if err := d.Set("created_at", ct.CreatedAt.Format(time.RFC3339)); err != nil {
+		log.Printf("[DEBUG] Unable to set mcs_kubernetes_clustertemplate created_at: %s", err)
+	}
+	if err := d.Set("updated_at", ct.UpdatedAt.Format(time.RFC3339)); err != nil {
+		log.Printf("[DEBUG] Unable to set mcs_kubernetes_clustertemplate updated_at: %s", err)
+	}
+	if !ct.DeprecatedAt.IsZero() {
+		if err := d.Set("deprecated_at", ct.DeprecatedAt.Format(time.RFC3339)); err != nil {
+			log.Printf("[DEBUG] Unable to set mcs_kubernetes_clustertemplate deprecated_at: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func resourceKubernetesClusterTemplateUpdate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(configer)
+	containerInfraClient, err := config.ContainerInfraV1Client(getRegion(d, config))
+	if err != nil {
+		return fmt.Errorf("error creating container infra client: %s", err)
+	}
+
+	patchOpts, err := clusterTemplatePatchOpts(d)
+	if err != nil {
+		return err
+	}
+	if len(patchOpts) > 0 {
+		_, err = clusterTemplatePatch(containerInfraClient, d.Id(), &patchOpts).Extract()
+		if err != nil {
+			return fmt.Errorf("error updating mcs_kubernetes_clustertemplate %s: %s", d.Id(), err)
+		}
+	}
+
+	return resourceKubernetesClusterTemplateRead(d, meta)
+}
+
+// clusterTemplatePatchOpts builds patch operations for the changed attributes.
+// Cleared string attributes are removed from the template.
+func clusterTemplatePatchOpts(d *schema.ResourceData) (nodeGroupClusterPatchOpts, error) {
+	keys := make([]string, 0, len(clusterTemplatePatchPaths))
+	for key := range clusterTemplatePatchPaths {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var patchOpts nodeGroupClusterPatchOpts
+	for _, key := range keys {
+		if !d.HasChange(key) {
+			continue
+		}
+		value := d.Get(key)
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				patchOpts = append(patchOpts, nodeGroupPatchParams{Op: "remove", Path: clusterTemplatePatchPaths[key]})
+				continue
+			}
+		case map[string]interface{}:
+			labels, err := extractKubernetesLabelsMap(v)
+			if err != nil {
+				return nil, err
+			}
+			value = labels
+		}
+		patchOpts = append(patchOpts, nodeGroupPatchParams{Op: "replace", Path: clusterTemplatePatchPaths[key], Value: value})
+	}
+	return patchOpts, nil
+}
+
+func resourceKubernetesClusterTemplateDelete(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(configer)
+	containerInfraClient, err := config.ContainerInfraV1Client(getRegion(d, config))
+	if err != nil {
+		return fmt.Errorf("error creating container infra client: %s", err)
+	}
+
+	if err := clusterTemplateDelete(containerInfraClient, d.Id()).ExtractErr(); err != nil {
+		return checkDeleted(d, err, "error deleting mcs_kubernetes_clustertemplate")
+	}
+
+	return nil
+}
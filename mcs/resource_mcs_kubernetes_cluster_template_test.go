@@ -0,0 +1,130 @@
+package mcs
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func TestAccKubernetesClusterTemplate_basic(t *testing.T) {
+	templateName := "testtemplate" + acctest.RandStringFromCharSet(8, acctest.CharSetAlphaNum)
+	resourceName := "mcs_kubernetes_clustertemplate." + templateName
+
+	var template, updatedTemplate clusterTemplate
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheckKubernetesClusterTemplate(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckKubernetesClusterTemplateDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccKubernetesClusterTemplateBasic(templateName, 10, `kube_tag = "v1.20.4"`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKubernetesClusterTemplateExists(resourceName, &template),
+					resource.TestCheckResourceAttr(resourceName, "name", templateName),
+					resource.TestCheckResourceAttr(resourceName, "image", osImageID),
+					resource.TestCheckResourceAttr(resourceName, "docker_volume_size", "10"),
+					resource.TestCheckResourceAttr(resourceName, "network_driver", "calico"),
+					resource.TestCheckResourceAttr(resourceName, "master_lb_enabled", "true"),
+					resource.TestCheckResourceAttr(resourceName, "labels.kube_tag", "v1.20.4"),
+					resource.TestCheckResourceAttr(resourceName, "version", "1.20.4"),
+				),
+			},
+			{
+				Config: testAccKubernetesClusterTemplateBasic(templateName, 20,
+					`kube_tag = "v1.20.4"
+    ingress_controller = "nginx"`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKubernetesClusterTemplateExists(resourceName, &updatedTemplate),
+					testAccCheckKubernetesClusterTemplateNotRecreated(&template, &updatedTemplate),
+					resource.TestCheckResourceAttr(resourceName, "docker_volume_size", "20"),
+					resource.TestCheckResourceAttr(resourceName, "labels.%", "2"),
+					resource.TestCheckResourceAttr(resourceName, "labels.ingress_controller", "nginx"),
+				),
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func testAccCheckKubernetesClusterTemplateExists(n string, template *clusterTemplate) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("cluster template not found: %s", n)
+		}
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("no id is set")
+		}
+
+		config := testAccProvider.Meta().(configer)
+		containerInfraClient, err := config.ContainerInfraV1Client(osRegionName)
+		if err != nil {
+			return fmt.Errorf("error creating container infra client: %s", err)
+		}
+
+		found, err := clusterTemplateGet(containerInfraClient, rs.Primary.ID).Extract()
+		if err != nil {
+			return err
+		}
+		if found.UUID != rs.Primary.ID {
+			return fmt.Errorf("cluster template not found")
+		}
+
+		*template = *found
+
+		return nil
+	}
+}
+
+func testAccCheckKubernetesClusterTemplateNotRecreated(before, after *clusterTemplate) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if before.UUID != after.UUID {
+			return fmt.Errorf("cluster template was recreated")
+		}
+		return nil
+	}
+}
+
+func testAccCheckKubernetesClusterTemplateDestroy(s *terraform.State) error {
+	config := testAccProvider.Meta().(configer)
+	containerInfraClient, err := config.ContainerInfraV1Client(osRegionName)
+	if err != nil {
+		return fmt.Errorf("error creating container infra client: %s", err)
+	}
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "mcs_kubernetes_clustertemplate" {
+			continue
+		}
+
+		_, err := clusterTemplateGet(containerInfraClient, rs.Primary.ID).Extract()
+		if err == nil {
+			return fmt.Errorf("cluster template still exists")
+		}
+	}
+
+	return nil
+}
+
+func testAccKubernetesClusterTemplateBasic(name string, dockerVolumeSize int, labels string) string {
+	return fmt.Sprintf(`
+resource "mcs_kubernetes_clustertemplate" "%[1]s" {
+  name               = "%[1]s"
+  image              = "%[2]s"
+  docker_volume_size = %[3]d
+  network_driver     = "calico"
+  master_lb_enabled  = true
+  labels = {
+    %[4]s
+  }
+}
+`, name, osImageID, dockerVolumeSize, labels)
+}
@@ -2,16 +2,14 @@ package mcs
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
+	"strings"
 	"testing"
 
-	"github.com/gophercloud/gophercloud"
-	"github.com/stretchr/testify/mock"
-
 	"github.com/hashicorp/terraform-plugin-sdk/helper/acctest"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/terraform"
-	uuid "github.com/satori/go.uuid"
 )
 
 const clusterResourceFixture = `
@@ -58,47 +56,18 @@ func checkClusterAttrs(resourceName string, cluster *clusterCreateOpts) resource
 	}
 }
 
-func TestMockAccKubernetesCluster_basic(t *testing.T) {
-	clientFixture := &ContainerClientFixture{}
-	clusterUUID := uuid.NewV4().String()
-
-	// Mock config methods
-	dummyConfig := &dummyConfig{}
-	dummyConfig.On("LoadAndValidate").Return(nil)
-	dummyConfig.On("ContainerInfraV1Client", "").Return(clientFixture, nil)
-	dummyConfig.On("getRegion").Return("")
-
-	// Create cluster fixtures
+func TestAccKubernetesCluster_basic(t *testing.T) {
 	clusterName := "testcluster" + acctest.RandStringFromCharSet(8, acctest.CharSetAlphaNum)
 	resourceName := "mcs_kubernetes_cluster." + clusterName
 
 	createClusterFixture := clusterFixture(clusterName, clusterTemplateID, osFlavorID,
 		osKeypairName, osNetworkID, osSubnetworkID, "MS1", 1)
-	jsonClusterFixture, _ := createClusterFixture.Map()
-
 	scaleFlavorClusterFixture := clusterFixture(clusterName, clusterTemplateID, osNewFlavorID,
 		osKeypairName, osNetworkID, osSubnetworkID, "MS1", 1)
-	scaleRequestFixture := map[string]interface{}{"action": "resize_masters", "payload": map[string]interface{}{"flavor": scaleFlavorClusterFixture.MasterFlavorID}}
-	jsonClusterScaleFixture, _ := scaleFlavorClusterFixture.Map()
-
-	// Mock API calls
-	clientFixture.On("ServiceURL", []string{"clusters"}).Return(testAccURL)
-	clientFixture.On("ServiceURL", []string{"clusters", clusterUUID}).Return(testAccURL)
-	clientFixture.On("ServiceURL", []string{"clusters", clusterUUID, "actions"}).Return(testAccURL)
-	// Create cluster
-	clientFixture.On("Post", testAccURL+"/clusters", jsonClusterFixture, mock.Anything, getRequestOpts(202)).Return(makeClusterCreateResponseFixture(clusterUUID), nil)
-	// Check it's status
-	clientFixture.On("Get", testAccURL+"/clusters/"+clusterUUID, mock.Anything, getRequestOpts(200)).Return(makeClusterGetResponseFixture(jsonClusterFixture, clusterUUID, clusterStatusRunning), nil).Times(6)
-	// Update cluster
-	clientFixture.On("Post", testAccURL+"/clusters/"+clusterUUID+"/actions", scaleRequestFixture, mock.Anything, getRequestOpts(200, 202)).Return(makeClusterGetResponseFixture(jsonClusterScaleFixture, clusterUUID, clusterStatusRunning), nil)
-	// Check it's status
-	clientFixture.On("Get", testAccURL+"/clusters/"+clusterUUID, mock.Anything, getRequestOpts(200)).Return(makeClusterGetResponseFixture(jsonClusterScaleFixture, clusterUUID, clusterStatusRunning), nil).Times(5)
-	// Delete cluster
-	clientFixture.On("Delete", testAccURL+"/clusters/"+clusterUUID, getRequestOpts()).Return(makeClusterDeleteResponseFixture(), nil)
-	// Check deleted
-	clientFixture.On("Get", testAccURL+"/clusters/"+clusterUUID, mock.Anything, getRequestOpts(200)).Return(gophercloud.ErrDefault404{}).Twice()
+	scaleMastersClusterFixture := clusterFixture(clusterName, clusterTemplateID, osNewFlavorID,
+		osKeypairName, osNetworkID, osSubnetworkID, "MS1", 3)
 
-	var cluster cluster
+	var cluster, scaledCluster cluster
 
 	resource.Test(t, resource.TestCase{
 		PreCheck:     func() { testAccPreCheckKubernetes(t) },
@@ -110,6 +79,11 @@ func TestMockAccKubernetesCluster_basic(t *testing.T) {
 				Check: resource.ComposeTestCheckFunc(
 					testAccCheckKubernetesClusterExists(resourceName, &cluster),
 					checkClusterAttrs(resourceName, createClusterFixture),
+					resource.TestCheckResourceAttrSet(resourceName, "k8s_config"),
+					resource.TestCheckResourceAttrPair(resourceName, "host", resourceName, "api_address"),
+					resource.TestCheckResourceAttrSet(resourceName, "cluster_ca_certificate"),
+					resource.TestCheckResourceAttrSet(resourceName, "client_certificate"),
+					resource.TestCheckResourceAttrSet(resourceName, "client_key"),
 				),
 			},
 			{
@@ -119,10 +93,350 @@ func TestMockAccKubernetesCluster_basic(t *testing.T) {
 					testAccCheckKubernetesClusterScaled(resourceName),
 				),
 			},
+			{
+				Config: testAccKubernetesClusterBasic(scaleMastersClusterFixture),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKubernetesClusterExists(resourceName, &scaledCluster),
+					testAccCheckKubernetesClusterNotRecreated(&cluster, &scaledCluster),
+					checkClusterAttrs(resourceName, scaleMastersClusterFixture),
+					resource.TestCheckResourceAttr(resourceName, "master_addresses.#", "3"),
+				),
+			},
+			{
+				Config:      testAccKubernetesClusterBasic(scaleFlavorClusterFixture),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("master_count can't be decreased"),
+			},
+		},
+	})
+}
+
+func TestAccKubernetesCluster_labels(t *testing.T) {
+	clusterName := "testcluster" + acctest.RandStringFromCharSet(8, acctest.CharSetAlphaNum)
+	resourceName := "mcs_kubernetes_cluster." + clusterName
+
+	createClusterFixture := clusterFixture(clusterName, clusterTemplateID, osFlavorID,
+		osKeypairName, osNetworkID, osSubnetworkID, "MS1", 1)
+
+	var cluster, updatedCluster cluster
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheckKubernetes(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckKubernetesClusterDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccKubernetesClusterLabels(createClusterFixture, `ingress_controller = "nginx"`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKubernetesClusterExists(resourceName, &cluster),
+					resource.TestCheckResourceAttr(resourceName, "labels.%", "1"),
+					resource.TestCheckResourceAttr(resourceName, "labels.ingress_controller", "nginx"),
+					testAccCheckKubernetesClusterLabel(&cluster, "kube_tag", true),
+				),
+			},
+			{
+				// The kube_tag label inherited from the cluster template is
+				// not in the configuration, but must not show up in the plan.
+				Config:             testAccKubernetesClusterLabels(createClusterFixture, `ingress_controller = "nginx"`),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: false,
+			},
+			{
+				Config: testAccKubernetesClusterLabels(createClusterFixture, `prometheus_monitoring = "true"`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKubernetesClusterExists(resourceName, &updatedCluster),
+					testAccCheckKubernetesClusterNotRecreated(&cluster, &updatedCluster),
+					resource.TestCheckResourceAttr(resourceName, "labels.%", "1"),
+					resource.TestCheckResourceAttr(resourceName, "labels.prometheus_monitoring", "true"),
+					testAccCheckKubernetesClusterLabel(&updatedCluster, clusterSystemLabel, true),
+					testAccCheckKubernetesClusterLabel(&updatedCluster, "ingress_controller", false),
+				),
+			},
+			{
+				// Imported labels must match the configured ones, i.e. the
+				// system and the template labels must be omitted.
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				// Labels added out of band show up as drift.
+				PreConfig: testAccKubernetesClusterPatchLabels(t, &cluster, func(labels map[string]string) {
+					labels["docker_registry_enabled"] = "true"
+				}),
+				Config:             testAccKubernetesClusterLabels(createClusterFixture, `prometheus_monitoring = "true"`),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				PreConfig: testAccKubernetesClusterPatchLabels(t, &cluster, func(labels map[string]string) {
+					delete(labels, "prometheus_monitoring")
+					labels["ingress_controller"] = "nginx"
+				}),
+				Config:             testAccKubernetesClusterLabels(createClusterFixture, `prometheus_monitoring = "true"`),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
 		},
 	})
 }
 
+func TestAccKubernetesCluster_immutableLabels(t *testing.T) {
+	clusterName := "testcluster" + acctest.RandStringFromCharSet(8, acctest.CharSetAlphaNum)
+	resourceName := "mcs_kubernetes_cluster." + clusterName
+
+	createClusterFixture := clusterFixture(clusterName, clusterTemplateID, osFlavorID,
+		osKeypairName, osNetworkID, osSubnetworkID, "MS1", 1)
+
+	var cluster cluster
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheckKubernetes(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckKubernetesClusterDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccKubernetesClusterLabels(createClusterFixture, `container_infra_prefix = "registry.example.com/"`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKubernetesClusterExists(resourceName, &cluster),
+					resource.TestCheckResourceAttr(resourceName, "labels.container_infra_prefix", "registry.example.com/"),
+				),
+			},
+			{
+				Config:      testAccKubernetesClusterLabels(createClusterFixture, `container_infra_prefix = "mirror.example.com/"`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("labels container_infra_prefix of mcs_kubernetes_cluster .* can't be updated in place"),
+			},
+		},
+	})
+}
+
+func TestAccKubernetesCluster_nodeGroups(t *testing.T) {
+	clusterName := "testcluster" + acctest.RandStringFromCharSet(8, acctest.CharSetAlphaNum)
+	resourceName := "mcs_kubernetes_cluster." + clusterName
+
+	createClusterFixture := clusterFixture(clusterName, clusterTemplateID, osFlavorID,
+		osKeypairName, osNetworkID, osSubnetworkID, "MS1", 1)
+	defaultFixture := nodeGroupFixture("default", osFlavorID, 2, 0, 0, false)
+	scaledFixture := nodeGroupFixture("default", osFlavorID, 3, 0, 0, false)
+	extraFixture := nodeGroupFixture("extra", osFlavorID, 1, 0, 0, false)
+
+	var cluster, updatedCluster cluster
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheckKubernetes(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckKubernetesClusterDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccKubernetesClusterNodeGroups(createClusterFixture, defaultFixture),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKubernetesClusterExists(resourceName, &cluster),
+					resource.TestCheckResourceAttr(resourceName, "status", string(clusterStatusRunning)),
+					resource.TestCheckResourceAttr(resourceName, "node_groups.#", "1"),
+					resource.TestCheckResourceAttr(resourceName, "node_groups.0.name", "default"),
+					resource.TestCheckResourceAttr(resourceName, "node_groups.0.node_count", "2"),
+					resource.TestCheckResourceAttr(resourceName, "node_groups.0.flavor_id", osFlavorID),
+				),
+			},
+			{
+				// Inline node groups are not imported, since they may be
+				// managed by mcs_kubernetes_node_group resources.
+				ResourceName:            resourceName,
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"node_groups"},
+			},
+			{
+				Config: testAccKubernetesClusterNodeGroups(createClusterFixture, scaledFixture, extraFixture),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKubernetesClusterExists(resourceName, &updatedCluster),
+					testAccCheckKubernetesClusterNotRecreated(&cluster, &updatedCluster),
+					resource.TestCheckResourceAttr(resourceName, "node_groups.#", "2"),
+					resource.TestCheckResourceAttr(resourceName, "node_groups.0.node_count", "3"),
+					resource.TestCheckResourceAttr(resourceName, "node_groups.1.name", "extra"),
+				),
+			},
+			{
+				Config: testAccKubernetesClusterNodeGroups(createClusterFixture, extraFixture),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKubernetesClusterExists(resourceName, &updatedCluster),
+					testAccCheckKubernetesClusterNotRecreated(&cluster, &updatedCluster),
+					resource.TestCheckResourceAttr(resourceName, "node_groups.#", "1"),
+					resource.TestCheckResourceAttr(resourceName, "node_groups.0.name", "extra"),
+					testAccCheckKubernetesClusterNodeGroupDeleted(resourceName, "default"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccKubernetesCluster_upgrade(t *testing.T) {
+	if nextClusterTemplateID == "" {
+		t.Skip("NEXT_CLUSTER_TEMPLATE_ID is not set, skipping cluster upgrade test.")
+	}
+
+	clusterName := "testcluster" + acctest.RandStringFromCharSet(8, acctest.CharSetAlphaNum)
+	resourceName := "mcs_kubernetes_cluster." + clusterName
+
+	createClusterFixture := clusterFixture(clusterName, clusterTemplateID, osFlavorID,
+		osKeypairName, osNetworkID, osSubnetworkID, "MS1", 1)
+	upgradeClusterFixture := clusterFixture(clusterName, nextClusterTemplateID, osFlavorID,
+		osKeypairName, osNetworkID, osSubnetworkID, "MS1", 1)
+	recreateClusterFixture := clusterFixture(clusterName, clusterTemplateID, osFlavorID,
+		osKeypairName+"-new", osNetworkID, osSubnetworkID, "MS1", 1)
+	upgradePolicy := `
+    rolling_enabled = true
+    max_surge       = 1
+    drain_timeout   = "5m"`
+
+	var cluster, upgradedCluster cluster
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheckKubernetes(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckKubernetesClusterDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccKubernetesClusterUpgradePolicy(createClusterFixture, upgradePolicy),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKubernetesClusterExists(resourceName, &cluster),
+					resource.TestCheckResourceAttr(resourceName, "upgrade_policy.0.max_surge", "1"),
+				),
+			},
+			{
+				Config: testAccKubernetesClusterUpgradePolicy(upgradeClusterFixture, upgradePolicy),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKubernetesClusterExists(resourceName, &upgradedCluster),
+					testAccCheckKubernetesClusterNotRecreated(&cluster, &upgradedCluster),
+					checkClusterAttrs(resourceName, upgradeClusterFixture),
+				),
+			},
+			{
+				Config:      testAccKubernetesClusterUpgradePolicy(createClusterFixture, upgradePolicy),
+				ExpectError: regexp.MustCompile("kubernetes version can't be downgraded"),
+			},
+			{
+				// A recreated cluster is not upgraded, so the template is not validated.
+				Config:             testAccKubernetesClusterUpgradePolicy(recreateClusterFixture, upgradePolicy),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestAccKubernetesCluster_deletionProtection(t *testing.T) {
+	clusterName := "testcluster" + acctest.RandStringFromCharSet(8, acctest.CharSetAlphaNum)
+	resourceName := "mcs_kubernetes_cluster." + clusterName
+
+	createClusterFixture := clusterFixture(clusterName, clusterTemplateID, osFlavorID,
+		osKeypairName, osNetworkID, osSubnetworkID, "MS1", 1)
+	replaceClusterFixture := clusterFixture(clusterName, clusterTemplateID, osFlavorID,
+		osKeypairName+"-new", osNetworkID, osSubnetworkID, "MS1", 1)
+
+	var cluster, unprotectedCluster cluster
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheckKubernetes(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckKubernetesClusterDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccKubernetesClusterDeletionProtection(createClusterFixture, true),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKubernetesClusterExists(resourceName, &cluster),
+					resource.TestCheckResourceAttr(resourceName, "deletion_protection", "true"),
+				),
+			},
+			{
+				Config:      testAccKubernetesClusterDeletionProtection(replaceClusterFixture, true),
+				ExpectError: regexp.MustCompile("can't be replaced while deletion_protection is enabled"),
+			},
+			{
+				Config:      testAccKubernetesClusterDeletionProtection(createClusterFixture, true),
+				Destroy:     true,
+				ExpectError: regexp.MustCompile("can't be deleted while deletion_protection is enabled"),
+			},
+			{
+				Config: testAccKubernetesClusterDeletionProtection(createClusterFixture, false),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKubernetesClusterExists(resourceName, &unprotectedCluster),
+					testAccCheckKubernetesClusterNotRecreated(&cluster, &unprotectedCluster),
+					resource.TestCheckResourceAttr(resourceName, "deletion_protection", "false"),
+				),
+			},
+		},
+	})
+}
+
+// testAccKubernetesClusterPatchLabels changes labels of the cluster out of
+// band, keeping the labels set by the service.
+func testAccKubernetesClusterPatchLabels(t *testing.T, cluster *cluster, change func(labels map[string]string)) func() {
+	return func() {
+		config := testAccProvider.Meta().(configer)
+		containerInfraClient, err := config.ContainerInfraV1Client(osRegionName)
+		if err != nil {
+			t.Fatalf("error creating container infra client: %s", err)
+		}
+		found, err := clusterGet(containerInfraClient, cluster.UUID).Extract()
+		if err != nil {
+			t.Fatalf("error retrieving cluster: %s", err)
+		}
+		change(found.Labels)
+		patchOpts := nodeGroupClusterPatchOpts{
+			nodeGroupPatchParams{Op: "replace", Path: "/labels", Value: found.Labels},
+		}
+		if _, err := clusterPatch(containerInfraClient, cluster.UUID, &patchOpts).Extract(); err != nil {
+			t.Fatalf("error patching cluster labels: %s", err)
+		}
+	}
+}
+
+// clusterSystemLabel is a label the service sets on all clusters.
+const clusterSystemLabel = "mcs.mail.ru/cluster-template"
+
+// testAccCheckKubernetesClusterLabel checks whether the cluster has the label.
+func testAccCheckKubernetesClusterLabel(cluster *cluster, key string, exists bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if _, ok := cluster.Labels[key]; ok != exists {
+			return fmt.Errorf("expected cluster label %s to exist: %t, got labels %v", key, exists, cluster.Labels)
+		}
+		return nil
+	}
+}
+
+func testAccCheckKubernetesClusterNodeGroupDeleted(n, name string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, _, err := getClusterAndResource(n, s)
+		if err != nil {
+			return err
+		}
+		config := testAccProvider.Meta().(configer)
+		containerInfraClient, err := config.ContainerInfraV1Client(osRegionName)
+		if err != nil {
+			return fmt.Errorf("error creating container infra client: %s", err)
+		}
+		ids, err := clusterNodeGroupIDs(containerInfraClient, rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+		if _, ok := ids[name]; ok {
+			return fmt.Errorf("node group %s still exists", name)
+		}
+		return nil
+	}
+}
+
+func testAccCheckKubernetesClusterNotRecreated(before, after *cluster) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if before.UUID != after.UUID {
+			return fmt.Errorf("cluster was recreated")
+		}
+		return nil
+	}
+}
+
 func testAccCheckKubernetesClusterExists(n string, cluster *cluster) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, found, err := getClusterAndResource(n, s)
@@ -211,3 +525,36 @@ func testAccKubernetesClusterBasic(createOpts *clusterCreateOpts) string {
 		createOpts.AvailabilityZone,
 	)
 }
+
+func testAccKubernetesClusterLabels(createOpts *clusterCreateOpts, labels string) string {
+	config := testAccKubernetesClusterBasic(createOpts)
+	i := strings.LastIndex(config, "}")
+	return config[:i] + "  labels = {\n    " + labels + "\n  }\n}\n"
+}
+
+func testAccKubernetesClusterNodeGroups(createOpts *clusterCreateOpts, nodeGroups ...*nodeGroupCreateOpts) string {
+	config := testAccKubernetesClusterBasic(createOpts)
+	i := strings.LastIndex(config, "}")
+	config = config[:i]
+	for _, ng := range nodeGroups {
+		config += fmt.Sprintf(`  node_groups {
+    name       = "%s"
+    node_count = %d
+    flavor_id  = "%s"
+  }
+`, ng.Name, ng.NodeCount, ng.FlavorID)
+	}
+	return config + "}\n"
+}
+
+func testAccKubernetesClusterUpgradePolicy(createOpts *clusterCreateOpts, policy string) string {
+	config := testAccKubernetesClusterBasic(createOpts)
+	i := strings.LastIndex(config, "}")
+	return config[:i] + "  upgrade_policy {" + policy + "\n  }\n}\n"
+}
+
+func testAccKubernetesClusterDeletionProtection(createOpts *clusterCreateOpts, enabled bool) string {
+	config := testAccKubernetesClusterBasic(createOpts)
+	i := strings.LastIndex(config, "}")
+	return config[:i] + fmt.Sprintf("  deletion_protection = %t\n}\n", enabled)
+}
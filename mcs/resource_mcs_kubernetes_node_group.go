@@ -6,12 +6,18 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/gophercloud/gophercloud"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 
 	"github.com/MailRuCloudSolutions/terraform-provider-mcs/mcs/internal/util/randutil"
 )
 
+// nodeGroupRolloutSuffixLength is the length of the random suffix of the name
+// of the node group created by a rollout.
+const nodeGroupRolloutSuffixLength = 5
+
 func resourceKubernetesNodeGroup() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceKubernetesNodeGroupCreate,
@@ -28,6 +34,8 @@ func resourceKubernetesNodeGroup() *schema.Resource {
 			Delete: schema.DefaultTimeout(operationDelete * time.Minute),
 		},
 
+		CustomizeDiff: resourceKubernetesNodeGroupCustomizeDiff,
+
 		Schema: map[string]*schema.Schema{
 			"cluster_id": {
 				Type:     schema.TypeString,
@@ -78,9 +86,10 @@ func resourceKubernetesNodeGroup() *schema.Resource {
 				},
 			},
 			"node_count": {
-				Type:     schema.TypeInt,
-				Required: true,
-				ForceNew: false,
+				Type:         schema.TypeInt,
+				Required:     true,
+				ForceNew:     false,
+				ValidateFunc: validation.IntAtLeast(1),
 				DiffSuppressFunc: func(k, old, new string, d *schema.ResourceData) bool {
 					// Suppress diff if node_count is managed by autoscaler when updating
 					if d.Get("autoscaling_enabled").(bool) && old != "" {
@@ -89,6 +98,13 @@ func resourceKubernetesNodeGroup() *schema.Resource {
 					return false
 				},
 			},
+			"nodes_to_remove": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				ForceNew: false,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      schema.HashString,
+			},
 			"max_nodes": {
 				Type:     schema.TypeInt,
 				Optional: true,
@@ -104,19 +120,19 @@ func resourceKubernetesNodeGroup() *schema.Resource {
 			"volume_size": {
 				Type:     schema.TypeInt,
 				Optional: true,
-				ForceNew: true,
+				ForceNew: false,
 				Computed: true,
 			},
 			"volume_type": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
+				ForceNew: false,
 				Computed: true,
 			},
 			"flavor_id": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
+				ForceNew: false,
 				Computed: true,
 			},
 			"autoscaling_enabled": {
@@ -150,6 +166,11 @@ func resourceKubernetesNodeGroup() *schema.Resource {
 				Computed: true,
 				Elem:     &schema.Schema{Type: schema.TypeString},
 			},
+			"nodes": nodeGroupNodesSchema(),
+			"actual_name": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
 		},
 	}
 }
@@ -161,6 +182,39 @@ func resourceKubernetesNodeGroupCreate(d *schema.ResourceData, meta interface{})
 		return fmt.Errorf("error creating container infra client: %s", err)
 	}
 
+	createOpts, err := expandNodeGroupCreateOpts(d)
+	if err != nil {
+		return err
+	}
+
+	s, err := nodeGroupCreate(containerInfraClient, createOpts).Extract()
+	if err != nil {
+		return fmt.Errorf("error creating mcs_kubernetes_node_group: %s", err)
+	}
+
+	// Store the node Group ID.
+	d.SetId(s.UUID)
+
+	stateConf := &resource.StateChangeConf{
+		Pending:      []string{string(clusterStatusReconciling)},
+		Target:       []string{string(clusterStatusRunning)},
+		Refresh:      nodeGroupStateRefreshFunc(containerInfraClient, s.ClusterID, s.UUID),
+		Timeout:      d.Timeout(schema.TimeoutCreate),
+		Delay:        createUpdateDelay,
+		PollInterval: createUpdatePollInterval,
+	}
+	_, err = stateConf.WaitForState()
+	if err != nil {
+		return fmt.Errorf(
+			"error waiting for mcs_kubernetes_cluster %s to become ready: %s", s.ClusterID, err)
+	}
+
+	log.Printf("[DEBUG] Created mcs_kubernetes_node_group %s", s.UUID)
+	return resourceKubernetesNodeGroupRead(d, meta)
+}
+
+// expandNodeGroupCreateOpts builds options to create the node group.
+func expandNodeGroupCreateOpts(d *schema.ResourceData) (*nodeGroupCreateOpts, error) {
 	createOpts := nodeGroupCreateOpts{
 		ClusterID:   d.Get("cluster_id").(string),
 		FlavorID:    d.Get("flavor_id").(string),
@@ -191,7 +245,7 @@ func resourceKubernetesNodeGroupCreate(d *schema.ResourceData, meta interface{})
 		rawLabels := lab.([]interface{})
 		labels, err := extractNodeGroupLabelsList(rawLabels)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		createOpts.Labels = labels
 	}
@@ -200,41 +254,48 @@ func resourceKubernetesNodeGroupCreate(d *schema.ResourceData, meta interface{})
 		rawTaints := tnt.([]interface{})
 		taints, err := extractNodeGroupTaintsList(rawTaints)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		createOpts.Taints = taints
 	}
 
-	if nodeCount := d.Get("node_count").(int); nodeCount > 0 {
-		createOpts.NodeCount = nodeCount
-	} else {
-		return fmt.Errorf("node_count parameter must be > 0")
-	}
-
-	s, err := nodeGroupCreate(containerInfraClient, &createOpts).Extract()
-	if err != nil {
-		return fmt.Errorf("error creating mcs_kubernetes_node_group: %s", err)
-	}
+	createOpts.NodeCount = d.Get("node_count").(int)
 
-	// Store the node Group ID.
-	d.SetId(s.UUID)
+	return &createOpts, nil
+}
 
-	stateConf := &resource.StateChangeConf{
-		Pending:      []string{string(clusterStatusReconciling)},
-		Target:       []string{string(clusterStatusRunning)},
-		Refresh:      kubernetesStateRefreshFunc(containerInfraClient, s.ClusterID),
-		Timeout:      d.Timeout(schema.TimeoutCreate),
-		Delay:        createUpdateDelay * time.Minute,
-		PollInterval: createUpdatePollInterval * time.Second,
+// resourceKubernetesNodeGroupCustomizeDiff validates the node group size at
+// plan time. When autoscaling is enabled node_count is managed by the
+// autoscaler, so only the bounds are validated. min_nodes and max_nodes are
+// computed, so the values which are not known yet are considered not set.
+func resourceKubernetesNodeGroupCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	var nodeCount, minNodes, maxNodes int
+	if d.NewValueKnown("node_count") {
+		nodeCount = d.Get("node_count").(int)
 	}
-	_, err = stateConf.WaitForState()
-	if err != nil {
-		return fmt.Errorf(
-			"error waiting for mcs_kubernetes_cluster %s to become ready: %s", s.ClusterID, err)
+	if d.NewValueKnown("min_nodes") {
+		minNodes = d.Get("min_nodes").(int)
+	}
+	if d.NewValueKnown("max_nodes") {
+		maxNodes = d.Get("max_nodes").(int)
 	}
+	autoscaling := d.NewValueKnown("autoscaling_enabled") && d.Get("autoscaling_enabled").(bool)
 
-	log.Printf("[DEBUG] Created mcs_kubernetes_node_group %s", s.UUID)
-	return resourceKubernetesNodeGroupRead(d, meta)
+	if err := validateNodeGroupSize(nodeCount, minNodes, maxNodes, autoscaling); err != nil {
+		return fmt.Errorf("invalid mcs_kubernetes_node_group %s size: %s", d.Get("name"), err)
+	}
+
+	// The nodes are resolved only when scaling, but their number can be
+	// checked already.
+	if d.Id() != "" && d.HasChange("node_count") && d.NewValueKnown("node_count") {
+		o, n := d.GetChange("node_count")
+		removed := o.(int) - n.(int)
+		if toRemove := d.Get("nodes_to_remove").(*schema.Set).Len(); removed > 0 && toRemove > removed {
+			return fmt.Errorf("invalid mcs_kubernetes_node_group %s nodes_to_remove: "+
+				"nodes_to_remove has %d nodes, but node_count decreases by %d", d.Get("name"), toRemove, removed)
+		}
+	}
+	return nil
 }
 
 func resourceKubernetesNodeGroupRead(d *schema.ResourceData, meta interface{}) error {
@@ -249,30 +310,22 @@ func resourceKubernetesNodeGroupRead(d *schema.ResourceData, meta interface{}) e
 		return checkDeleted(d, err, "error retrieving mcs_kubernetes_node_group")
 	}
 
-	log.Printf("[DEBUG] Retrieved mcs_kubernetes_node_group %s: %#v", d.Id(), s)
+	log.Printf("[DEBUG] Retrieved mcs_kubernetes_node_group %s", d.Id())
 
-	// Get and check labels list.
-	rawLabels := d.Get("labels").([]interface{})
-	labels, err := extractNodeGroupLabelsList(rawLabels)
-	if err != nil {
-		return err
-	}
-
-	if err := d.Set("labels", flattenNodeGroupLabelsList(labels)); err != nil {
+	// Labels and taints set by the service are not managed by the provider.
+	if err := d.Set("labels", flattenNodeGroupLabelsList(filterSystemNodeGroupLabels(s.Labels))); err != nil {
 		return fmt.Errorf("unable to set mcs_kubernetes_node_group labels: %s", err)
 	}
-
-	// Get and check taints list.
-	rawTaints := d.Get("taints").([]interface{})
-	taints, err := extractNodeGroupTaintsList(rawTaints)
-	if err != nil {
-		return err
-	}
-	if err := d.Set("taints", flattenNodeGroupTaintsList(taints)); err != nil {
+	if err := d.Set("taints", flattenNodeGroupTaintsList(filterSystemNodeGroupTaints(s.Taints))); err != nil {
 		return fmt.Errorf("unable to set mcs_kubernetes_node_group taints: %s", err)
 	}
 
-	d.Set("name", s.Name)
+	// The node group created by a rollout has a suffix in its name, so the
+	// configured name is kept while the node group is the same.
+	if s.Name != d.Get("actual_name").(string) {
+		d.Set("name", s.Name)
+	}
+	d.Set("actual_name", s.Name)
 	d.Set("node_count", s.NodeCount)
 	d.Set("max_nodes", s.MaxNodes)
 	d.Set("min_nodes", s.MinNodes)
@@ -282,11 +335,14 @@ func resourceKubernetesNodeGroupRead(d *schema.ResourceData, meta interface{}) e
 	d.Set("autoscaling_enabled", s.Autoscaling)
 	d.Set("cluster_id", s.ClusterID)
 	d.Set("availability_zones", s.AvailabilityZones)
+	if err := d.Set("nodes", flattenNodes(s.Nodes)); err != nil {
+		return fmt.Errorf("unable to set mcs_kubernetes_node_group nodes: %s", err)
+	}
 
-	if err := d.Set("created_at", getTimestamp(&s.CreatedAt)); err != nil {
+	if err := d.Set("created_at", getTimestamp(s.CreatedAt)); err != nil {
 		log.Printf("[DEBUG] Unable to set mcs_kubernetes_node_group created_at: %s", err)
 	}
-	if err := d.Set("updated_at", getTimestamp(&s.UpdatedAt)); err != nil {
+	if err := d.Set("updated_at", getTimestamp(s.UpdatedAt)); err != nil {
 		log.Printf("[DEBUG] Unable to set mcs_kubernetes_node_group updated_at: %s", err)
 	}
 
@@ -300,11 +356,24 @@ func resourceKubernetesNodeGroupUpdate(d *schema.ResourceData, meta interface{})
 		return fmt.Errorf("error creating container infra client: %s", err)
 	}
 
+	// The new node group is created with the whole configuration, so there
+	// is nothing left to update in place.
+	if d.HasChanges("flavor_id", "volume_size", "volume_type") {
+		// All the nodes of the old node group are removed by the rollout.
+		if d.Get("nodes_to_remove").(*schema.Set).Len() > 0 {
+			log.Printf("[DEBUG] Ignoring nodes_to_remove of mcs_kubernetes_node_group %s rolled out with all its nodes", d.Id())
+		}
+		if err := rolloutKubernetesNodeGroup(d, containerInfraClient); err != nil {
+			return err
+		}
+		return resourceKubernetesNodeGroupRead(d, meta)
+	}
+
 	stateConf := &resource.StateChangeConf{
-		Refresh:      kubernetesStateRefreshFunc(containerInfraClient, d.Get("cluster_id").(string)),
+		Refresh:      nodeGroupStateRefreshFunc(containerInfraClient, d.Get("cluster_id").(string), d.Id()),
 		Timeout:      d.Timeout(schema.TimeoutUpdate),
-		Delay:        createUpdateDelay * time.Minute,
-		PollInterval: createUpdatePollInterval * time.Second,
+		Delay:        createUpdateDelay,
+		PollInterval: createUpdatePollInterval,
 		Pending:      []string{string(clusterStatusReconciling)},
 		Target:       []string{string(clusterStatusRunning)},
 	}
@@ -317,6 +386,14 @@ func resourceKubernetesNodeGroupUpdate(d *schema.ResourceData, meta interface{})
 		scaleOpts := nodeGroupScaleOpts{
 			Delta: d.Get("node_count").(int) - s.NodeCount,
 		}
+		if scaleOpts.Delta < 0 {
+			nodesToRemove := d.Get("nodes_to_remove").(*schema.Set).List()
+			scaleOpts.NodesToRemove, err = expandNodeGroupNodesToRemove(nodesToRemove, s.Nodes, -scaleOpts.Delta)
+			if err != nil {
+				return fmt.Errorf("error scaling mcs_kubernetes_node_group %s: %s", d.Id(), err)
+			}
+			log.Printf("[DEBUG] Removing nodes %v of mcs_kubernetes_node_group %s", scaleOpts.NodesToRemove, d.Id())
+		}
 
 		_, err = nodeGroupScale(containerInfraClient, d.Id(), &scaleOpts).Extract()
 		if err != nil {
@@ -401,6 +478,80 @@ func resourceKubernetesNodeGroupUpdate(d *schema.ResourceData, meta interface{})
 	return resourceKubernetesNodeGroupRead(d, meta)
 }
 
+// rolloutKubernetesNodeGroup replaces the node group with a new one having the
+// updated flavor and volume. The new node group is created next to the old one
+// and the old one is deleted only after the new one is running, so the
+// workloads can be moved without the capacity of the cluster going down.
+// Until the new node group is running the old values are kept in the state,
+// so the rollout is retried on the next apply. Once the new node group is
+// running the resource refers to it, and the old node group which is failed
+// to delete is reported in the error.
+func rolloutKubernetesNodeGroup(d *schema.ResourceData, client ContainerClient) error {
+	d.Partial(true)
+	oldID := d.Id()
+	clusterID := d.Get("cluster_id").(string)
+
+	createOpts, err := expandNodeGroupCreateOpts(d)
+	if err != nil {
+		return err
+	}
+	// Node group names are unique within the cluster.
+	createOpts.Name = fmt.Sprintf("%s-%s", createOpts.Name, randutil.RandomName(nodeGroupRolloutSuffixLength))
+
+	log.Printf("[INFO] Rolling out mcs_kubernetes_node_group %s: creating node group %s", oldID, createOpts.Name)
+	s, err := nodeGroupCreate(client, createOpts).Extract()
+	if err != nil {
+		return fmt.Errorf("error creating node group to roll out mcs_kubernetes_node_group %s: %s", oldID, err)
+	}
+
+	stateConf := &resource.StateChangeConf{
+		Pending:      []string{string(clusterStatusReconciling)},
+		Target:       []string{string(clusterStatusRunning)},
+		Refresh:      nodeGroupStateRefreshFunc(client, clusterID, s.UUID),
+		Timeout:      d.Timeout(schema.TimeoutUpdate),
+		Delay:        createUpdateDelay,
+		PollInterval: createUpdatePollInterval,
+	}
+	log.Printf("[INFO] Rolling out mcs_kubernetes_node_group %s: waiting for node group %s to become ready", oldID, s.UUID)
+	if _, err := stateConf.WaitForState(); err != nil {
+		log.Printf("[INFO] Rolling out mcs_kubernetes_node_group %s: deleting failed node group %s", oldID, s.UUID)
+		if err := nodeGroupDelete(client, s.UUID).ExtractErr(); err != nil {
+			log.Printf("[WARN] Unable to delete node group %s: %s", s.UUID, err)
+		}
+		return fmt.Errorf(
+			"error waiting for node group %s to roll out mcs_kubernetes_node_group %s: %s", s.UUID, oldID, err)
+	}
+
+	// The new node group is running, from now on the resource refers to it.
+	d.SetId(s.UUID)
+	d.Set("actual_name", createOpts.Name)
+	d.Partial(false)
+
+	log.Printf("[INFO] Rolling out mcs_kubernetes_node_group %s: deleting node group %s", s.UUID, oldID)
+	if err := nodeGroupDelete(client, oldID).ExtractErr(); err != nil {
+		if _, ok := err.(gophercloud.ErrDefault404); !ok {
+			return fmt.Errorf("mcs_kubernetes_node_group is rolled out to node group %s, "+
+				"but the old node group %s can't be deleted, it must be deleted manually: %s", s.UUID, oldID, err)
+		}
+	}
+
+	stateConf = &resource.StateChangeConf{
+		Pending:      []string{string(clusterStatusReconciling)},
+		Target:       []string{string(clusterStatusRunning)},
+		Refresh:      nodeGroupStateRefreshFunc(client, clusterID, oldID),
+		Timeout:      d.Timeout(schema.TimeoutUpdate),
+		Delay:        nodeGroupDeleteDelay,
+		PollInterval: deletePollInterval,
+	}
+	if _, err := stateConf.WaitForState(); err != nil {
+		return fmt.Errorf("mcs_kubernetes_node_group is rolled out to node group %s, "+
+			"but the old node group %s is not deleted, it must be checked manually: %s", s.UUID, oldID, err)
+	}
+
+	log.Printf("[INFO] Rolled out mcs_kubernetes_node_group %s to %s", oldID, s.UUID)
+	return nil
+}
+
 func resourceKubernetesNodeGroupDelete(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(configer)
 	containerInfraClient, err := config.ContainerInfraV1Client(getRegion(d, config))
@@ -415,10 +566,10 @@ func resourceKubernetesNodeGroupDelete(d *schema.ResourceData, meta interface{})
 	stateConf := &resource.StateChangeConf{
 		Pending:      []string{string(clusterStatusReconciling)},
 		Target:       []string{string(clusterStatusRunning)},
-		Refresh:      kubernetesStateRefreshFunc(containerInfraClient, d.Get("cluster_id").(string)),
+		Refresh:      nodeGroupStateRefreshFunc(containerInfraClient, d.Get("cluster_id").(string), d.Id()),
 		Timeout:      d.Timeout(schema.TimeoutDelete),
-		Delay:        nodeGroupDeleteDelay * time.Second,
-		PollInterval: deletePollInterval * time.Second,
+		Delay:        nodeGroupDeleteDelay,
+		PollInterval: deletePollInterval,
 	}
 	_, err = stateConf.WaitForState()
 	if err != nil {
@@ -2,12 +2,20 @@ package mcs
 
 import (
 	"fmt"
+	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	th "github.com/gophercloud/gophercloud/testhelper"
+	fake "github.com/gophercloud/gophercloud/testhelper/client"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/acctest"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+	"github.com/stretchr/testify/assert"
 )
 
 func nodeGroupFixture(name, flavorID string, count, max, min int, autoscaling bool) *nodeGroupCreateOpts {
@@ -60,12 +68,14 @@ func TestAccKubernetesNodeGroup_basic(t *testing.T) {
 					testAccCheckKubernetesClusterExists(clusterResourceName, &cluster),
 					testAccCheckKubernetesNodeGroupExists(nodeGroupResourceName, clusterResourceName, &nodeGroup),
 					checkNodeGroupAttrs(nodeGroupResourceName, ngFixture),
+					resource.TestCheckResourceAttr(nodeGroupResourceName, "nodes.#", strconv.Itoa(ngFixture.NodeCount)),
 				),
 			},
 			{
 				Config: testAccKubernetesNodeGroupBasic(clusterName, testAccKubernetesClusterBasic(createClusterFixture), ngNodeCountScaleFixture),
 				Check: resource.ComposeTestCheckFunc(
 					resource.TestCheckResourceAttr(nodeGroupResourceName, "node_count", strconv.Itoa(ngNodeCountScaleFixture.NodeCount)),
+					resource.TestCheckResourceAttr(nodeGroupResourceName, "nodes.#", strconv.Itoa(ngNodeCountScaleFixture.NodeCount)),
 					testAccCheckKubernetesNodeGroupScaled(nodeGroupResourceName),
 				),
 			},
@@ -115,6 +125,191 @@ func checkNodeGroupPatchAttrs(resourceName string, nodeGroup *nodeGroupCreateOpt
 	}
 }
 
+func TestAccKubernetesNodeGroup_invalidSize(t *testing.T) {
+	clusterName := "testcluster" + acctest.RandStringFromCharSet(8, acctest.CharSetAlphaNum)
+	clusterResource := testAccKubernetesClusterBasic(clusterFixture(clusterName, clusterTemplateID, osFlavorID,
+		osKeypairName, osNetworkID, osSubnetworkID, "MS1", 1))
+	nodeGroupName := "testng" + acctest.RandStringFromCharSet(8, acctest.CharSetAlphaNum)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheckKubernetes(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckKubernetesClusterDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccKubernetesNodeGroupBasic(clusterName, clusterResource,
+					nodeGroupFixture(nodeGroupName, osFlavorID, 2, 0, 0, true)),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("min_nodes and max_nodes must be set when autoscaling_enabled is true"),
+			},
+			{
+				Config: testAccKubernetesNodeGroupBasic(clusterName, clusterResource,
+					nodeGroupFixture(nodeGroupName, osFlavorID, 2, 2, 3, false)),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("min_nodes 3 must not be greater than max_nodes 2"),
+			},
+			{
+				Config: testAccKubernetesNodeGroupBasic(clusterName, clusterResource,
+					nodeGroupFixture(nodeGroupName, osFlavorID, 6, 5, 1, false)),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("node_count 6 must not be greater than max_nodes 5"),
+			},
+			{
+				// The autoscaler owns the node count, so it is not checked.
+				Config: testAccKubernetesNodeGroupBasic(clusterName, clusterResource,
+					nodeGroupFixture(nodeGroupName, osFlavorID, 6, 5, 1, true)),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestAccKubernetesNodeGroup_nodesToRemove(t *testing.T) {
+	var nodeGroup nodeGroup
+
+	clusterName := "testcluster" + acctest.RandStringFromCharSet(8, acctest.CharSetAlphaNum)
+	clusterResource := testAccKubernetesClusterBasic(clusterFixture(clusterName, clusterTemplateID, osFlavorID,
+		osKeypairName, osNetworkID, osSubnetworkID, "MS1", 1))
+	clusterResourceName := "mcs_kubernetes_cluster." + clusterName
+
+	nodeGroupName := "testng" + acctest.RandStringFromCharSet(8, acctest.CharSetAlphaNum)
+	ngFixture := nodeGroupFixture(nodeGroupName, osFlavorID, 3, 5, 1, false)
+	ngScaleDownFixture := nodeGroupFixture(nodeGroupName, osFlavorID, 2, 5, 1, false)
+	ngScaleDownAgainFixture := nodeGroupFixture(nodeGroupName, osFlavorID, 1, 5, 1, false)
+	nodeGroupResourceName := "mcs_kubernetes_node_group." + nodeGroupName
+
+	var steps []resource.TestStep
+	steps = []resource.TestStep{
+		{
+			Config: testAccKubernetesNodeGroupBasic(clusterName, clusterResource, ngFixture),
+			Check: resource.ComposeTestCheckFunc(
+				testAccCheckKubernetesNodeGroupExists(nodeGroupResourceName, clusterResourceName, &nodeGroup),
+				resource.TestCheckResourceAttr(nodeGroupResourceName, "nodes.#", "3"),
+				// The node to remove is known only after the node group is
+				// created, so the configs which remove it are set here.
+				func(*terraform.State) error {
+					nodesToRemove := []string{nodeGroup.Nodes[0].Name}
+					steps[1].Config = testAccKubernetesNodeGroupNodesToRemove(clusterName, clusterResource,
+						ngScaleDownFixture, nodesToRemove)
+					steps[2].Config = testAccKubernetesNodeGroupNodesToRemove(clusterName, clusterResource,
+						ngScaleDownAgainFixture, nodesToRemove)
+					return nil
+				},
+			),
+		},
+		{
+			Check: resource.ComposeTestCheckFunc(
+				resource.TestCheckResourceAttr(nodeGroupResourceName, "node_count", "2"),
+				resource.TestCheckResourceAttr(nodeGroupResourceName, "nodes.#", "2"),
+				testAccCheckKubernetesNodeGroupNodeRemoved(nodeGroupResourceName, &nodeGroup),
+			),
+		},
+		{
+			// The removed node is ignored when the node group is scaled down again.
+			Check: resource.ComposeTestCheckFunc(
+				resource.TestCheckResourceAttr(nodeGroupResourceName, "node_count", "1"),
+				resource.TestCheckResourceAttr(nodeGroupResourceName, "nodes.#", "1"),
+				testAccCheckKubernetesNodeGroupNodeRemoved(nodeGroupResourceName, &nodeGroup),
+			),
+		},
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheckKubernetes(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckKubernetesClusterDestroy,
+		Steps:        steps,
+	})
+}
+
+func TestAccKubernetesNodeGroup_rollout(t *testing.T) {
+	var nodeGroup nodeGroup
+
+	clusterName := "testcluster" + acctest.RandStringFromCharSet(8, acctest.CharSetAlphaNum)
+	clusterResource := testAccKubernetesClusterBasic(clusterFixture(clusterName, clusterTemplateID, osFlavorID,
+		osKeypairName, osNetworkID, osSubnetworkID, "MS1", 1))
+	clusterResourceName := "mcs_kubernetes_cluster." + clusterName
+
+	nodeGroupName := "testng" + acctest.RandStringFromCharSet(8, acctest.CharSetAlphaNum)
+	ngFixture := nodeGroupFixture(nodeGroupName, osFlavorID, 1, 5, 1, false)
+	ngNewFlavorFixture := nodeGroupFixture(nodeGroupName, osNewFlavorID, 1, 5, 1, false)
+	nodeGroupResourceName := "mcs_kubernetes_node_group." + nodeGroupName
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheckKubernetes(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckKubernetesClusterDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccKubernetesNodeGroupBasic(clusterName, clusterResource, ngFixture),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKubernetesNodeGroupExists(nodeGroupResourceName, clusterResourceName, &nodeGroup),
+					checkNodeGroupAttrs(nodeGroupResourceName, ngFixture),
+				),
+			},
+			{
+				Config: testAccKubernetesNodeGroupBasic(clusterName, clusterResource, ngNewFlavorFixture),
+				Check: resource.ComposeTestCheckFunc(
+					checkNodeGroupAttrs(nodeGroupResourceName, ngNewFlavorFixture),
+					testAccCheckKubernetesNodeGroupRolledOut(nodeGroupResourceName, &nodeGroup),
+				),
+			},
+		},
+	})
+}
+
+func TestRolloutKubernetesNodeGroupDeleteFailed(t *testing.T) {
+	th.SetupHTTP()
+	defer th.TeardownHTTP()
+
+	defer func(delay, interval time.Duration) {
+		createUpdateDelay, createUpdatePollInterval = delay, interval
+	}(createUpdateDelay, createUpdatePollInterval)
+	createUpdateDelay, createUpdatePollInterval = 0, 0
+
+	th.Mux.HandleFunc("/nodegroups", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(t, r, "POST")
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, `{"uuid": "new"}`)
+	})
+	th.Mux.HandleFunc("/nodegroups/new", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(t, r, "GET")
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"uuid": "new", "state": "RUNNING"}`)
+	})
+	th.Mux.HandleFunc("/nodegroups/old", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(t, r, "DELETE")
+		w.WriteHeader(http.StatusConflict)
+	})
+	th.Mux.HandleFunc("/clusters/cluster", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(t, r, "GET")
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"uuid": "cluster", "new_status": "RUNNING"}`)
+	})
+
+	d := schema.TestResourceDataRaw(t, resourceKubernetesNodeGroup().Schema, map[string]interface{}{
+		"cluster_id": "cluster",
+		"name":       "ng",
+		"flavor_id":  "new-flavor",
+		"node_count": 1,
+	})
+	d.SetId("old")
+
+	err := rolloutKubernetesNodeGroup(d, fake.ServiceClient())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "the old node group old can't be deleted")
+
+	// The resource refers to the new node group, so the rollout is not repeated.
+	state := d.State()
+	assert.Equal(t, "new", state.ID)
+	assert.Equal(t, "new-flavor", state.Attributes["flavor_id"])
+	assert.Regexp(t, "^ng-[a-z0-9]{5}$", state.Attributes["actual_name"])
+}
+
 func testAccCheckKubernetesNodeGroupExists(n, clusterResourceName string, nodeGroup *nodeGroup) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, found, err := getNgAndResource(n, s)
@@ -160,6 +355,63 @@ func testAccCheckKubernetesNodeGroupScaled(n string) resource.TestCheckFunc {
 	}
 }
 
+func testAccCheckKubernetesNodeGroupRolledOut(n string, oldNodeGroup *nodeGroup) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, found, err := getNgAndResource(n, s)
+		if err != nil {
+			return err
+		}
+
+		if rs.Primary.ID == oldNodeGroup.UUID {
+			return fmt.Errorf("node group %s was not rolled out", rs.Primary.ID)
+		}
+		if found.FlavorID != rs.Primary.Attributes["flavor_id"] {
+			return fmt.Errorf("mismatched flavor_id")
+		}
+		if found.Name != rs.Primary.Attributes["actual_name"] {
+			return fmt.Errorf("mismatched actual_name %s of node group %s", rs.Primary.Attributes["actual_name"], found.Name)
+		}
+		if !regexp.MustCompile("^" + oldNodeGroup.Name + "-[a-z0-9]{5}$").MatchString(found.Name) {
+			return fmt.Errorf("unexpected name %s of rolled out node group %s", found.Name, oldNodeGroup.Name)
+		}
+
+		config := testAccProvider.Meta().(*config)
+		containerInfraClient, err := config.ContainerInfraV1Client(osRegionName)
+		if err != nil {
+			return fmt.Errorf("error creating container infra client: %s", err)
+		}
+		if _, err := nodeGroupGet(containerInfraClient, oldNodeGroup.UUID).Extract(); err == nil {
+			return fmt.Errorf("node group %s still exists", oldNodeGroup.UUID)
+		}
+		return nil
+	}
+}
+
+// testAccCheckKubernetesNodeGroupNodeRemoved checks that the first node of
+// the created node group is removed.
+func testAccCheckKubernetesNodeGroupNodeRemoved(n string, createdNodeGroup *nodeGroup) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, found, err := getNgAndResource(n, s)
+		if err != nil {
+			return err
+		}
+
+		removed := createdNodeGroup.Nodes[0]
+		for _, node := range found.Nodes {
+			if node.UUID == removed.UUID {
+				return fmt.Errorf("node %s is not removed", removed.Name)
+			}
+		}
+		count, _ := strconv.Atoi(rs.Primary.Attributes["nodes.#"])
+		for i := 0; i < count; i++ {
+			if rs.Primary.Attributes[fmt.Sprintf("nodes.%d.uuid", i)] == removed.UUID {
+				return fmt.Errorf("node %s is still in nodes", removed.Name)
+			}
+		}
+		return nil
+	}
+}
+
 func testAccCheckKubernetesNodeGroupPatched(n string) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, found, err := getNgAndResource(n, s)
@@ -212,3 +464,10 @@ func testAccKubernetesNodeGroupBasic(clusterName, clusterResource string, fixtur
 		fixture.Autoscaling,
 	)
 }
+
+func testAccKubernetesNodeGroupNodesToRemove(clusterName, clusterResource string, fixture *nodeGroupCreateOpts,
+	nodesToRemove []string) string {
+	config := testAccKubernetesNodeGroupBasic(clusterName, clusterResource, fixture)
+	i := strings.LastIndex(config, "}")
+	return config[:i] + fmt.Sprintf("  nodes_to_remove = [\"%s\"]\n}\n", strings.Join(nodesToRemove, `", "`))
+}
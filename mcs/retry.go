@@ -0,0 +1,199 @@
+package mcs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseDelay   = 30 * time.Millisecond
+	defaultRetryMaxDelay    = 30 * time.Second
+)
+
+var defaultRetryableStatusCodes = []int{
+	http.StatusConflict,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// retryPolicy describes how failed requests to MCS APIs are retried.
+type retryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	StatusCodes []int
+}
+
+func defaultRetryPolicy() retryPolicy {
+	return retryPolicy{
+		MaxAttempts: defaultRetryMaxAttempts,
+		BaseDelay:   defaultRetryBaseDelay,
+		MaxDelay:    defaultRetryMaxDelay,
+		StatusCodes: defaultRetryableStatusCodes,
+	}
+}
+
+// extractRetryPolicy builds retryPolicy from the provider retry block.
+func extractRetryPolicy(d *schema.ResourceData) (retryPolicy, error) {
+	policy := defaultRetryPolicy()
+	v, ok := d.GetOk("retry")
+	if !ok {
+		return policy, nil
+	}
+	blocks := v.([]interface{})
+	if len(blocks) == 0 || blocks[0] == nil {
+		return policy, nil
+	}
+	retry := blocks[0].(map[string]interface{})
+
+	policy.MaxAttempts = retry["max_attempts"].(int)
+
+	var err error
+	if policy.BaseDelay, err = time.ParseDuration(retry["base_delay"].(string)); err != nil {
+		return policy, fmt.Errorf("invalid retry base_delay: %s", err)
+	}
+	if policy.MaxDelay, err = time.ParseDuration(retry["max_delay"].(string)); err != nil {
+		return policy, fmt.Errorf("invalid retry max_delay: %s", err)
+	}
+	if policy.MaxDelay < policy.BaseDelay {
+		return policy, fmt.Errorf("retry max_delay must not be less than base_delay")
+	}
+
+	if codes := retry["retryable_status_codes"].([]interface{}); len(codes) > 0 {
+		policy.StatusCodes = make([]int, 0, len(codes))
+		for _, code := range codes {
+			policy.StatusCodes = append(policy.StatusCodes, code.(int))
+		}
+	}
+	return policy, nil
+}
+
+// apply installs the policy into the provider client shared by all service clients.
+func (p retryPolicy) apply(client *gophercloud.ProviderClient) {
+	client.MaxBackoffRetries = uint(p.MaxAttempts)
+	client.RetryBackoffFunc = func(ctx context.Context, respErr *gophercloud.ErrUnexpectedResponseCode, err error, failCount uint) error {
+		return p.wait(ctx, respErr.Method, respErr.URL, err, failCount)
+	}
+	client.RetryFunc = func(ctx context.Context, method, url string, options *gophercloud.RequestOpts, err error, failCount uint) error {
+		return p.wait(ctx, method, url, err, failCount)
+	}
+}
+
+// wait sleeps before the next attempt. It returns err if the request
+// must not be retried.
+func (p retryPolicy) wait(ctx context.Context, method, url string, err error, failCount uint) error {
+	if failCount >= uint(p.MaxAttempts) {
+		return err
+	}
+	respErr, ok := unexpectedResponse(err)
+	if !ok || !p.retryable(respErr.Actual) {
+		return err
+	}
+
+	delay := p.delay(failCount)
+	if retryAfter, ok := parseRetryAfter(respErr.ResponseHeader); ok {
+		if retryAfter > p.MaxDelay {
+			return err
+		}
+		delay = retryAfter
+	}
+
+	log.Printf("[DEBUG] %s %s failed with status %d, retrying in %s (attempt %d of %d)",
+		method, url, respErr.Actual, delay, failCount+1, p.MaxAttempts)
+
+	if ctx == nil {
+		time.Sleep(delay)
+		return nil
+	}
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return err
+	}
+}
+
+func (p retryPolicy) retryable(code int) bool {
+	for _, c := range p.StatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns exponential backoff with jitter for the given attempt:
+// a random value between half and full of base_delay*2^(attempt-1),
+// capped by max_delay.
+func (p retryPolicy) delay(failCount uint) time.Duration {
+	delay := p.MaxDelay
+	if failCount > 0 && failCount < 32 {
+		if d := p.BaseDelay << (failCount - 1); d > 0 && d < p.MaxDelay {
+			delay = d
+		}
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func parseRetryAfter(header http.Header) (time.Duration, bool) {
+	retryAfter := header.Get("Retry-After")
+	if retryAfter == "" {
+		return 0, false
+	}
+	if v, err := strconv.ParseUint(retryAfter, 10, 32); err == nil {
+		return time.Duration(v) * time.Second, true
+	}
+	if v, err := time.Parse(http.TimeFormat, retryAfter); err == nil {
+		d := time.Until(v)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func unexpectedResponse(err error) (gophercloud.ErrUnexpectedResponseCode, bool) {
+	switch e := err.(type) {
+	case gophercloud.ErrUnexpectedResponseCode:
+		return e, true
+	case *gophercloud.ErrUnexpectedResponseCode:
+		return *e, true
+	case gophercloud.ErrDefault400:
+		return e.ErrUnexpectedResponseCode, true
+	case gophercloud.ErrDefault401:
+		return e.ErrUnexpectedResponseCode, true
+	case gophercloud.ErrDefault403:
+		return e.ErrUnexpectedResponseCode, true
+	case gophercloud.ErrDefault404:
+		return e.ErrUnexpectedResponseCode, true
+	case gophercloud.ErrDefault405:
+		return e.ErrUnexpectedResponseCode, true
+	case gophercloud.ErrDefault408:
+		return e.ErrUnexpectedResponseCode, true
+	case gophercloud.ErrDefault409:
+		return e.ErrUnexpectedResponseCode, true
+	case gophercloud.ErrDefault429:
+		return e.ErrUnexpectedResponseCode, true
+	case gophercloud.ErrDefault500:
+		return e.ErrUnexpectedResponseCode, true
+	case gophercloud.ErrDefault503:
+		return e.ErrUnexpectedResponseCode, true
+	}
+	return gophercloud.ErrUnexpectedResponseCode{}, false
+}
@@ -0,0 +1,128 @@
+package mcs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyServer fails the first failures requests with status and then succeeds.
+func flakyServer(t *testing.T, failures int32, status int, header http.Header) (*gophercloud.ServiceClient, *int32) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := &gophercloud.ServiceClient{
+		ProviderClient: &gophercloud.ProviderClient{},
+		Endpoint:       server.URL + "/",
+	}
+	return client, &attempts
+}
+
+func testRetryPolicy() retryPolicy {
+	return retryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    10 * time.Millisecond,
+		StatusCodes: defaultRetryableStatusCodes,
+	}
+}
+
+func TestRetryPolicyRetriesRetryableStatus(t *testing.T) {
+	for _, status := range []int{
+		http.StatusConflict,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	} {
+		client, attempts := flakyServer(t, 2, status, nil)
+		testRetryPolicy().apply(client.ProviderClient)
+
+		var body map[string]interface{}
+		_, err := client.Get(client.ServiceURL("clusters"), &body, getRequestOpts(200))
+		assert.NoError(t, err, "status %d", status)
+		assert.EqualValues(t, 3, atomic.LoadInt32(attempts), "status %d", status)
+	}
+}
+
+func TestRetryPolicyGivesUpAfterMaxAttempts(t *testing.T) {
+	client, attempts := flakyServer(t, 5, http.StatusServiceUnavailable, nil)
+	testRetryPolicy().apply(client.ProviderClient)
+
+	var body map[string]interface{}
+	_, err := client.Get(client.ServiceURL("clusters"), &body, getRequestOpts(200))
+	assert.IsType(t, gophercloud.ErrDefault503{}, err)
+	assert.EqualValues(t, 3, atomic.LoadInt32(attempts))
+}
+
+func TestRetryPolicySkipsNonRetryableStatus(t *testing.T) {
+	client, attempts := flakyServer(t, 1, http.StatusBadRequest, nil)
+	testRetryPolicy().apply(client.ProviderClient)
+
+	var body map[string]interface{}
+	_, err := client.Get(client.ServiceURL("clusters"), &body, getRequestOpts(200))
+	assert.IsType(t, gophercloud.ErrDefault400{}, err)
+	assert.EqualValues(t, 1, atomic.LoadInt32(attempts))
+}
+
+func TestRetryPolicyHonorsRetryAfter(t *testing.T) {
+	header := http.Header{"Retry-After": []string{"1"}}
+	client, attempts := flakyServer(t, 1, http.StatusTooManyRequests, header)
+	policy := testRetryPolicy()
+	policy.MaxDelay = 2 * time.Second
+	policy.apply(client.ProviderClient)
+
+	start := time.Now()
+	var body map[string]interface{}
+	_, err := client.Get(client.ServiceURL("clusters"), &body, getRequestOpts(200))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, atomic.LoadInt32(attempts))
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestRetryPolicyRetryAfterAboveMaxDelay(t *testing.T) {
+	header := http.Header{"Retry-After": []string{"60"}}
+	client, attempts := flakyServer(t, 1, http.StatusTooManyRequests, header)
+	testRetryPolicy().apply(client.ProviderClient)
+
+	var body map[string]interface{}
+	_, err := client.Get(client.ServiceURL("clusters"), &body, getRequestOpts(200))
+	assert.Error(t, err)
+	assert.EqualValues(t, 1, atomic.LoadInt32(attempts))
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := retryPolicy{
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay:  time.Second,
+	}
+	for failCount, max := range map[uint]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		3:  400 * time.Millisecond,
+		4:  800 * time.Millisecond,
+		5:  time.Second,
+		40: time.Second,
+	} {
+		delay := policy.delay(failCount)
+		assert.GreaterOrEqual(t, int64(delay), int64(max/2), "attempt %d", failCount)
+		assert.LessOrEqual(t, int64(delay), int64(max), "attempt %d", failCount)
+	}
+}
@@ -24,6 +24,10 @@ func upgradeURL(c ContainerClient, api string, id string) string {
 	return c.ServiceURL(api, id, "actions", "upgrade")
 }
 
+func nodeGroupsURL(c ContainerClient, api string, id string) string {
+	return c.ServiceURL(api, id, "nodegroups")
+}
+
 func scaleURL(c ContainerClient, api string, id string) string {
 	return c.ServiceURL(api, id, "actions", "scale")
 }
@@ -81,3 +81,11 @@ func isOperationNotSupported(d string, types ...string) bool {
 	}
 	return false
 }
+
+// validateDuration checks that the value can be parsed by time.ParseDuration.
+func validateDuration(v interface{}, k string) (warns []string, errs []error) {
+	if _, err := time.ParseDuration(v.(string)); err != nil {
+		errs = append(errs, fmt.Errorf("%q must be a valid duration: %s", k, err))
+	}
+	return
+}